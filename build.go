@@ -4,11 +4,16 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
 	"time"
 
+	"github.com/Masterminds/semver/v3"
 	"github.com/paketo-buildpacks/packit/v2"
 	"github.com/paketo-buildpacks/packit/v2/cargo"
 	"github.com/paketo-buildpacks/packit/v2/chronos"
+	"github.com/paketo-buildpacks/packit/v2/fs"
 	"github.com/paketo-buildpacks/packit/v2/postal"
 	"github.com/paketo-buildpacks/packit/v2/sbom"
 	"github.com/paketo-buildpacks/packit/v2/scribe"
@@ -29,60 +34,186 @@ type DependencyManager interface {
 
 //go:generate faux --interface SBOMGenerator --output fakes/sbom_generator.go
 type SBOMGenerator interface {
+	Generate(dir string) (sbom.SBOM, error)
 	GenerateFromDependency(dependency postal.Dependency, dir string) (sbom.SBOM, error)
 }
 
+//go:generate faux --interface DotnetCLI --output fakes/dotnet_cli.go
+type DotnetCLI interface {
+	Execute(dotnetRoot, workingDir string, env []string, args ...string) error
+}
+
 func Build(entryResolver EntryResolver,
 	dependencyManager DependencyManager,
 	sbomGenerator SBOMGenerator,
+	dotnetCLI DotnetCLI,
 	logger scribe.Emitter,
 	clock chronos.Clock,
 ) packit.BuildFunc {
 	return func(context packit.BuildContext) (packit.BuildResult, error) {
 		logger.Title("%s %s", context.BuildpackInfo.Name, context.BuildpackInfo.Version)
+
+		projectDir, err := ProjectDir(context.WorkingDir)
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		// The NuGet.Config from the binding is needed by every command that
+		// downloads packages, starting with the workload install
+		configLayer, nugetConfigPath, err := contributeNugetConfig(context, logger)
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
 		logger.Process("Resolving .NET Core SDK version")
 
+		// finish adds the layers contributed alongside the SDK installed at
+		// dotnetRoot to the result
+		finish := func(result packit.BuildResult, dotnetRoot string) (packit.BuildResult, error) {
+			if configLayer != nil {
+				result.Layers = append(result.Layers, *configLayer)
+			}
+
+			cacheLayer, err := contributeNugetCache(context, logger)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			if cacheLayer != nil {
+				result.Layers = append(result.Layers, *cacheLayer)
+
+				err = restoreMsbuildSdks(MsbuildSdksFromPlan(context.Plan.Entries), dotnetRoot, cacheLayer.Path, nugetConfigPath, dotnetCLI, logger, clock)
+				if err != nil {
+					return packit.BuildResult{}, err
+				}
+
+				toolsLayer, err := restoreTools(context, projectDir, dotnetRoot, cacheLayer.Path, nugetConfigPath, dotnetCLI, logger, clock)
+				if err != nil {
+					return packit.BuildResult{}, err
+				}
+
+				if toolsLayer != nil {
+					result.Layers = append(result.Layers, *toolsLayer)
+				}
+			}
+
+			return result, nil
+		}
+
 		planEntry, entries := entryResolver.Resolve(DotnetDependency, context.Plan.Entries, Priorities)
 		logger.Candidates(entries)
 
-		version, _ := planEntry.Metadata["version"].(string)
 		versionSource, _ := planEntry.Metadata["version-source"].(string)
-
-		var sdkDependency postal.Dependency
-		var err error
 		if versionSource == "global.json" {
+			if globalJsonPath, ok := planEntry.Metadata["global-json-path"].(string); ok {
+				logger.Subprocess("Using global.json at %s", globalJsonPath)
+			}
+
+			version, _ := planEntry.Metadata["version"].(string)
 			rollforward, _ := planEntry.Metadata["roll-forward"].(string)
+			allowPrerelease, _ := planEntry.Metadata["allow-prerelease"].(bool)
+
+			// Only SDKs inside the application are used: an SDK elsewhere on the
+			// build image would not exist in the run image
+			var sdkPaths []string
+			for _, sdkPath := range metadataStrings(planEntry.Metadata["sdk-paths"]) {
+				if sdkPath != HostSdkPath && !withinDir(sdkPath, context.WorkingDir) {
+					logger.Subprocess("Ignoring sdk path %s outside the application directory", sdkPath)
+					continue
+				}
+				sdkPaths = append(sdkPaths, sdkPath)
+			}
 
-			logger.Subprocess("Resolving with roll-forward strategy '%s'", rollforward)
+			localSdk, err := FindLocalSdk(sdkPaths, version, rollforward, allowPrerelease)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
 
-			sdkDependency, err = ResolveWithRollforward(
-				filepath.Join(context.CNBPath, "buildpack.toml"),
-				version,
-				rollforward,
-				context.Stack,
-			)
+			if localSdk != nil {
+				logger.Subprocess("Using .NET Core SDK %s from %s", localSdk.Version, localSdk.Root)
+				logger.Subprocess("Skipping the end-of-life check, resolution report and workload installation for the local SDK")
+				logger.Break()
+
+				sdkLayer, err := context.Layers.Get("dotnet-core-sdk")
+				if err != nil {
+					return packit.BuildResult{}, err
+				}
+
+				sdkLayer, err = sdkLayer.Reset()
+				if err != nil {
+					return packit.BuildResult{}, err
+				}
+
+				sdkLayer.Metadata = map[string]interface{}{
+					"sdk-path":    localSdk.Root,
+					"sdk-version": localSdk.Version,
+				}
+
+				launch, build := entryResolver.MergeLayerTypes(DotnetDependency, context.Plan.Entries)
+				sdkLayer.Build, sdkLayer.Launch = build, launch
+
+				setEnvironment(&sdkLayer, localSdk.Root, launch)
+				logger.EnvironmentVariables(sdkLayer)
+
+				return finish(packit.BuildResult{
+					Layers: []packit.Layer{
+						sdkLayer,
+					},
+				}, localSdk.Root)
+			}
+		}
+
+		eolPolicy, err := LoadEndOfLifePolicy()
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		sdkEntries := SelectSdkEntries(planEntry, entries)
+		report := NewResolutionReport(entries, sdkEntries)
+
+		var sdkDependencies []postal.Dependency
+		for _, entry := range sdkEntries {
+			sdkDependency, resolution, err := resolveDependency(entry, dependencyManager, logger, context)
 			if err != nil {
 				return packit.BuildResult{}, err
 			}
-		} else {
-			sdkDependency, err = dependencyManager.Resolve(
-				filepath.Join(context.CNBPath, "buildpack.toml"),
-				planEntry.Name,
-				version,
-				context.Stack)
+			report.Resolutions = append(report.Resolutions, resolution)
+
+			logger.SelectedDependency(entry, sdkDependency, clock.Now())
+
+			err = eolPolicy.Check(sdkDependency, clock.Now(), logger)
 			if err != nil {
 				return packit.BuildResult{}, err
 			}
+
+			if !slices.ContainsFunc(sdkDependencies, func(d postal.Dependency) bool { return d.Version == sdkDependency.Version }) {
+				sdkDependencies = append(sdkDependencies, sdkDependency)
+			}
+		}
+
+		report.Log(logger)
+		reportJSON, err := report.JSON()
+		if err != nil {
+			return packit.BuildResult{}, err
 		}
 
-		logger.SelectedDependency(planEntry, sdkDependency, clock.Now())
+		// Deliver SDKs in ascending version order so that the dotnet host from
+		// the newest SDK is the one left in the layer
+		slices.SortStableFunc(sdkDependencies, func(a, b postal.Dependency) int {
+			return compareVersions(a.Version, b.Version)
+		})
+
+		workloads, err := ResolveWorkloads(projectDir, context.WorkingDir, logger)
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
 
 		sdkLayer, err := context.Layers.Get("dotnet-core-sdk")
 		if err != nil {
 			return packit.BuildResult{}, err
 		}
 
-		bom := dependencyManager.GenerateBillOfMaterials(sdkDependency)
+		bom := dependencyManager.GenerateBillOfMaterials(sdkDependencies...)
 		launch, build := entryResolver.MergeLayerTypes(DotnetDependency, context.Plan.Entries)
 
 		var buildMetadata packit.BuildMetadata
@@ -90,65 +221,149 @@ func Build(entryResolver EntryResolver,
 			buildMetadata.BOM = bom
 		}
 
+		bundledRuntimes := map[string]interface{}{}
+		var bundledBOM []packit.BOMEntry
+		for _, sdkDependency := range sdkDependencies {
+			details, err := LoadSdkDetails(filepath.Join(context.CNBPath, "buildpack.toml"), sdkDependency)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+			details.Log(sdkDependency.Version, logger)
+
+			if runtimes := details.BundledRuntimes(); runtimes != nil {
+				bundledRuntimes[sdkDependency.Version] = runtimes
+			}
+
+			// SDKs from different feature bands can bundle the same runtime
+			for _, entry := range details.BOMEntries() {
+				if !slices.ContainsFunc(bundledBOM, func(e packit.BOMEntry) bool { return reflect.DeepEqual(e, entry) }) {
+					bundledBOM = append(bundledBOM, entry)
+				}
+			}
+		}
+
 		var launchMetadata packit.LaunchMetadata
 		if launch {
-			launchMetadata.BOM = bom
+			launchMetadata.BOM = slices.Concat(bom, bundledBOM)
 		}
 
-		dependencyChecksum := sdkDependency.Checksum
-		//nolint Ignore SA1019, informed usage of deprecated field
-		if sdkDependency.SHA256 != "" {
-			dependencyChecksum = sdkDependency.SHA256
+		dependencyChecksums := map[string]interface{}{}
+		for _, sdkDependency := range sdkDependencies {
+			dependencyChecksums[sdkDependency.Version] = dependencyChecksum(sdkDependency)
 		}
 
-		cachedChecksum, ok := sdkLayer.Metadata["dependency-checksum"].(string)
+		// Check the cache per SDK: cached SDKs that are no longer required
+		// force a reset of the layer, and only missing SDKs are delivered
+		cachedChecksums, _ := sdkLayer.Metadata["dependency-checksums"].(map[string]interface{})
 
-		if ok && cargo.Checksum(dependencyChecksum).MatchString(cachedChecksum) {
+		var newestCachedVersion string
+		for version := range cachedChecksums {
+			if newestCachedVersion == "" || compareVersions(version, newestCachedVersion) > 0 {
+				newestCachedVersion = version
+			}
+		}
+
+		var missingDependencies []postal.Dependency
+		olderThanCached := false
+		for _, sdkDependency := range sdkDependencies {
+			cachedChecksum, ok := cachedChecksums[sdkDependency.Version].(string)
+			if !ok || !cargo.Checksum(dependencyChecksum(sdkDependency)).MatchString(cachedChecksum) {
+				missingDependencies = append(missingDependencies, sdkDependency)
+
+				// Delivering an SDK older than a cached one would replace the dotnet
+				// host of the newest SDK
+				if newestCachedVersion != "" && compareVersions(sdkDependency.Version, newestCachedVersion) < 0 {
+					olderThanCached = true
+				}
+			}
+		}
+
+		// Workloads are installed into the SDK installation, so a changed set of
+		// workloads requires a fresh layer
+		workloadsChanged := !slices.Equal(metadataStrings(sdkLayer.Metadata["workloads"]), workloads)
+
+		if len(missingDependencies) == 0 && len(cachedChecksums) == len(sdkDependencies) && !workloadsChanged {
 			logger.Process(fmt.Sprintf("Reusing cached layer %s", sdkLayer.Path))
 			logger.Break()
 
+			sdkLayer.Metadata["resolution-report"] = reportJSON
 			sdkLayer.Build, sdkLayer.Launch, sdkLayer.Cache = build, launch, build || launch
+			setEnvironment(&sdkLayer, sdkLayer.Path, launch)
 
-			return packit.BuildResult{
+			return finish(packit.BuildResult{
 				Layers: []packit.Layer{
 					sdkLayer,
 				},
 				Build:  buildMetadata,
 				Launch: launchMetadata,
-			}, nil
+			}, sdkLayer.Path)
 		}
 
 		logger.Process("Executing build process")
 
-		sdkLayer, err = sdkLayer.Reset()
-		if err != nil {
-			return packit.BuildResult{}, err
+		if len(cachedChecksums) == 0 || len(cachedChecksums)+len(missingDependencies) != len(sdkDependencies) || olderThanCached || workloadsChanged {
+			sdkLayer, err = sdkLayer.Reset()
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+			missingDependencies = sdkDependencies
 		}
 
-		logger.Subprocess("Installing %s %s", ".NET Core SDK", sdkDependency.Version)
-		duration, err := clock.Measure(func() error {
-			return dependencyManager.Deliver(sdkDependency, context.CNBPath, sdkLayer.Path, context.Platform.Path)
-		})
-		if err != nil {
-			return packit.BuildResult{}, err
+		for _, sdkDependency := range missingDependencies {
+			logger.Subprocess("Installing %s %s", ".NET Core SDK", sdkDependency.Version)
+			duration, err := clock.Measure(func() error {
+				return dependencyManager.Deliver(sdkDependency, context.CNBPath, sdkLayer.Path, context.Platform.Path)
+			})
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			logger.Action("Completed in %s", duration.Round(time.Millisecond))
 		}
 
-		logger.Action("Completed in %s", duration.Round(time.Millisecond))
+		if len(workloads) > 0 {
+			logger.Subprocess("Installing workloads %s", strings.Join(workloads, ", "))
+			duration, err := clock.Measure(func() error {
+				args := append([]string{"workload", "install"}, workloads...)
+				if nugetConfigPath != "" {
+					args = append(args, "--configfile", nugetConfigPath)
+				}
+
+				return dotnetCLI.Execute(sdkLayer.Path, context.WorkingDir, nil, args...)
+			})
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			logger.Action("Completed in %s", duration.Round(time.Millisecond))
+		}
 		logger.Break()
 
 		sdkLayer.Metadata = map[string]interface{}{
-			"dependency-checksum": dependencyChecksum,
+			"dependency-checksums": dependencyChecksums,
+			"resolution-report":    reportJSON,
+		}
+		if len(workloads) > 0 {
+			sdkLayer.Metadata["workloads"] = workloads
+		}
+		if len(bundledRuntimes) > 0 {
+			sdkLayer.Metadata["bundled-runtimes"] = bundledRuntimes
 		}
 
 		sdkLayer.Build, sdkLayer.Launch, sdkLayer.Cache = build, launch, build || launch
 
-		sdkLayer.BuildEnv.Prepend("PATH", sdkLayer.Path, string(os.PathListSeparator))
+		setEnvironment(&sdkLayer, sdkLayer.Path, launch)
 		logger.EnvironmentVariables(sdkLayer)
 
 		logger.GeneratingSBOM(sdkLayer.Path)
 		var sbomContent sbom.SBOM
-		duration, err = clock.Measure(func() error {
-			sbomContent, err = sbomGenerator.GenerateFromDependency(sdkDependency, sdkLayer.Path)
+		duration, err := clock.Measure(func() error {
+			if len(sdkDependencies) == 1 {
+				sbomContent, err = sbomGenerator.GenerateFromDependency(sdkDependencies[0], sdkLayer.Path)
+				return err
+			}
+
+			sbomContent, err = sbomGenerator.Generate(sdkLayer.Path)
 			return err
 		})
 		if err != nil {
@@ -164,12 +379,220 @@ func Build(entryResolver EntryResolver,
 			return packit.BuildResult{}, err
 		}
 
-		return packit.BuildResult{
+		return finish(packit.BuildResult{
 			Layers: []packit.Layer{
 				sdkLayer,
 			},
 			Build:  buildMetadata,
 			Launch: launchMetadata,
-		}, nil
+		}, sdkLayer.Path)
+	}
+}
+
+// setEnvironment puts the dotnet host on the PATH, points DOTNET_ROOT at the
+// SDK installation and sets the .NET CLI defaults for subsequent buildpacks,
+// and at launch time when the layer is a launch layer.
+func setEnvironment(layer *packit.Layer, dotnetRoot string, launch bool) {
+	layer.BuildEnv.Prepend("PATH", dotnetRoot, string(os.PathListSeparator))
+	layer.BuildEnv.Override("DOTNET_ROOT", dotnetRoot)
+	if launch {
+		layer.LaunchEnv.Override("DOTNET_ROOT", dotnetRoot)
+	}
+
+	for _, variable := range CliEnvironmentDefaults {
+		value, ok := os.LookupEnv(variable.Setting)
+		if !ok {
+			value = variable.Default
+		}
+
+		layer.BuildEnv.Default(variable.Name, value)
+		if launch {
+			layer.LaunchEnv.Default(variable.Name, value)
+		}
+	}
+}
+
+// restoreTools restores the tools listed in the local tool manifest found
+// between projectDir and the application root with `dotnet tool restore`,
+// into the NuGet package cache at packagesDir, where `dotnet tool run` and
+// `dotnet <command>` look for them. The commands of the tools are put on the
+// PATH through shims in the dotnet-tools layer, which is cached by the
+// checksum of the manifest. Tools are restored with the NuGet.Config at
+// nugetConfigPath, when given. It returns nil when there is no manifest.
+func restoreTools(context packit.BuildContext, projectDir, dotnetRoot, packagesDir, nugetConfigPath string, dotnetCLI DotnetCLI, logger scribe.Emitter, clock chronos.Clock) (*packit.Layer, error) {
+	manifest, err := FindToolManifest(projectDir, context.WorkingDir)
+	if err != nil {
+		return nil, err
+	}
+
+	if manifest == nil || len(manifest.Tools) == 0 {
+		return nil, nil
+	}
+
+	// The restore is a no-op for tools that are already in the package cache,
+	// and the cache can be reset while the manifest stays the same, so it runs
+	// on every build
+	logger.Process("Restoring .NET tools from %s", manifest.Path)
+
+	args := []string{"tool", "restore", "--tool-manifest", manifest.Path}
+	if nugetConfigPath != "" {
+		args = append(args, "--configfile", nugetConfigPath)
+	}
+
+	logger.Subprocess("Restoring %s", strings.Join(manifest.ToolIDs(), ", "))
+	duration, err := clock.Measure(func() error {
+		return dotnetCLI.Execute(dotnetRoot, context.WorkingDir, []string{fmt.Sprintf("NUGET_PACKAGES=%s", packagesDir)}, args...)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Action("Completed in %s", duration.Round(time.Millisecond))
+	logger.Break()
+
+	checksum, err := fs.NewChecksumCalculator().Sum(manifest.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	toolsLayer, err := context.Layers.Get("dotnet-tools")
+	if err != nil {
+		return nil, err
+	}
+
+	shimPath := filepath.Join(toolsLayer.Path, "bin")
+
+	cachedChecksum, _ := toolsLayer.Metadata["manifest-checksum"].(string)
+	if cachedChecksum == checksum {
+		logger.Process(fmt.Sprintf("Reusing cached layer %s", toolsLayer.Path))
+		logger.Break()
+
+		toolsLayer.Build, toolsLayer.Cache = true, true
+		toolsLayer.BuildEnv.Prepend("PATH", shimPath, string(os.PathListSeparator))
+
+		return &toolsLayer, nil
+	}
+
+	toolsLayer, err = toolsLayer.Reset()
+	if err != nil {
+		return nil, err
+	}
+
+	err = os.MkdirAll(shimPath, os.ModePerm)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tool shim directory: %w", err)
+	}
+
+	for _, id := range manifest.ToolIDs() {
+		for _, command := range manifest.Tools[id].Commands {
+			err = os.WriteFile(filepath.Join(shimPath, command), []byte(fmt.Sprintf("#!/bin/sh\nexec dotnet tool run %s \"$@\"\n", command)), 0755)
+			if err != nil {
+				return nil, fmt.Errorf("failed to write tool shim: %w", err)
+			}
+		}
+	}
+
+	toolsLayer.Metadata = map[string]interface{}{
+		"manifest-checksum": checksum,
+	}
+
+	toolsLayer.Build, toolsLayer.Cache = true, true
+	toolsLayer.BuildEnv.Prepend("PATH", shimPath, string(os.PathListSeparator))
+	logger.EnvironmentVariables(toolsLayer)
+
+	return &toolsLayer, nil
+}
+
+func resolveDependency(entry packit.BuildpackPlanEntry, dependencyManager DependencyManager, logger scribe.Emitter, context packit.BuildContext) (postal.Dependency, Resolution, error) {
+	version, _ := entry.Metadata["version"].(string)
+	versionSource, _ := entry.Metadata["version-source"].(string)
+
+	resolution := Resolution{
+		VersionSource: versionSource,
+		Constraint:    version,
+	}
+
+	if versionSource != "global.json" {
+		sdkDependency, err := dependencyManager.Resolve(
+			filepath.Join(context.CNBPath, "buildpack.toml"),
+			entry.Name,
+			version,
+			context.Stack)
+		if err != nil {
+			return postal.Dependency{}, Resolution{}, err
+		}
+
+		if version != "" {
+			resolution.ConstraintsTried = []string{version}
+		}
+		resolution.Version = sdkDependency.Version
+
+		return sdkDependency, resolution, nil
+	}
+
+	rollforward, _ := entry.Metadata["roll-forward"].(string)
+	allowPrerelease, _ := entry.Metadata["allow-prerelease"].(bool)
+	errorMessage, _ := entry.Metadata["error-message"].(string)
+
+	logger.Subprocess("Resolving with roll-forward strategy '%s'", rollforward)
+
+	sdkDependency, tried, err := resolveWithRollforward(
+		filepath.Join(context.CNBPath, "buildpack.toml"),
+		version,
+		rollforward,
+		allowPrerelease,
+		context.Stack,
+	)
+	if err != nil {
+		if errorMessage != "" {
+			return postal.Dependency{}, Resolution{}, fmt.Errorf("%s: %w", errorMessage, err)
+		}
+		return postal.Dependency{}, Resolution{}, err
+	}
+
+	resolution.RollForward = rollforward
+	resolution.ConstraintsTried = tried
+	resolution.Version = sdkDependency.Version
+
+	return sdkDependency, resolution, nil
+}
+
+func dependencyChecksum(dependency postal.Dependency) string {
+	//nolint Ignore SA1019, informed usage of deprecated field
+	if dependency.SHA256 != "" {
+		return dependency.SHA256
+	}
+
+	return dependency.Checksum
+}
+
+// compareVersions orders semantic versions, falling back to a string
+// comparison for versions that cannot be parsed.
+func compareVersions(a, b string) int {
+	aVersion, aErr := semver.NewVersion(a)
+	bVersion, bErr := semver.NewVersion(b)
+	if aErr != nil || bErr != nil {
+		return strings.Compare(a, b)
+	}
+
+	return aVersion.Compare(bVersion)
+}
+
+// metadataStrings converts a list-valued build plan metadata field to a
+// []string. Lists decoded from the buildpack plan TOML are []interface{}.
+func metadataStrings(value interface{}) []string {
+	switch v := value.(type) {
+	case []string:
+		return v
+	case []interface{}:
+		var values []string
+		for _, item := range v {
+			if str, ok := item.(string); ok {
+				values = append(values, str)
+			}
+		}
+		return values
+	default:
+		return nil
 	}
 }
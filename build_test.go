@@ -3,16 +3,20 @@ package dotnetcoresdk_test
 import (
 	"bytes"
 	"errors"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"regexp"
+	"strings"
 	"testing"
+	"time"
 
 	dotnetcoresdk "github.com/paketo-buildpacks/dotnet-core-sdk"
 	"github.com/paketo-buildpacks/dotnet-core-sdk/fakes"
 	"github.com/paketo-buildpacks/packit/v2"
 	"github.com/paketo-buildpacks/packit/v2/chronos"
+	"github.com/paketo-buildpacks/packit/v2/fs"
 	"github.com/paketo-buildpacks/packit/v2/sbom"
 	"github.com/paketo-buildpacks/packit/v2/scribe"
 
@@ -36,6 +40,7 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 		entryResolver     *fakes.EntryResolver
 		dependencyManager *fakes.DependencyManager
 		sbomGenerator     *fakes.SBOMGenerator
+		dotnetCLI         *fakes.DotnetCLI
 
 		build packit.BuildFunc
 	)
@@ -96,12 +101,15 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 		sbomGenerator = &fakes.SBOMGenerator{}
 		sbomGenerator.GenerateFromDependencyCall.Returns.SBOM = sbom.SBOM{}
 
+		dotnetCLI = &fakes.DotnetCLI{}
+
 		buffer = bytes.NewBuffer(nil)
 
 		build = dotnetcoresdk.Build(
 			entryResolver,
 			dependencyManager,
 			sbomGenerator,
+			dotnetCLI,
 			scribe.NewEmitter(buffer),
 			chronos.DefaultClock,
 		)
@@ -145,13 +153,37 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 
 		Expect(layer.Name).To(Equal("dotnet-core-sdk"))
 		Expect(layer.BuildEnv).To(Equal(packit.Environment{
-			"PATH.prepend": filepath.Join(layersDir, "dotnet-core-sdk"),
-			"PATH.delim":   string(os.PathListSeparator),
+			"PATH.prepend":                               filepath.Join(layersDir, "dotnet-core-sdk"),
+			"PATH.delim":                                 string(os.PathListSeparator),
+			"DOTNET_ROOT.override":                       filepath.Join(layersDir, "dotnet-core-sdk"),
+			"DOTNET_CLI_TELEMETRY_OPTOUT.default":        "true",
+			"DOTNET_NOLOGO.default":                      "true",
+			"DOTNET_SKIP_FIRST_TIME_EXPERIENCE.default":  "true",
+			"DOTNET_GENERATE_ASPNET_CERTIFICATE.default": "false",
+		}))
+		Expect(layer.LaunchEnv).To(Equal(packit.Environment{
+			"DOTNET_ROOT.override":                       filepath.Join(layersDir, "dotnet-core-sdk"),
+			"DOTNET_CLI_TELEMETRY_OPTOUT.default":        "true",
+			"DOTNET_NOLOGO.default":                      "true",
+			"DOTNET_SKIP_FIRST_TIME_EXPERIENCE.default":  "true",
+			"DOTNET_GENERATE_ASPNET_CERTIFICATE.default": "false",
 		}))
 		Expect(layer.Path).To(Equal(filepath.Join(layersDir, "dotnet-core-sdk")))
-		Expect(layer.Metadata).To(Equal(map[string]interface{}{
-			"dependency-checksum": "sha256:some-sha",
+		Expect(layer.Metadata).To(HaveLen(2))
+		Expect(layer.Metadata).To(HaveKeyWithValue("dependency-checksums", map[string]interface{}{
+			"some-version": "sha256:some-sha",
 		}))
+		Expect(layer.Metadata["resolution-report"]).To(MatchJSON(`{
+			"candidates": [],
+			"resolutions": [
+				{
+					"version-source": "some-source",
+					"constraint": "2.5.x",
+					"constraints-tried": ["2.5.x"],
+					"version": "some-version"
+				}
+			]
+		}`))
 
 		Expect(layer.Build).To(BeTrue())
 		Expect(layer.Launch).To(BeTrue())
@@ -307,10 +339,224 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 		Expect(sbomGenerator.GenerateFromDependencyCall.Receives.Dir).To(Equal(filepath.Join(layersDir, "dotnet-core-sdk")))
 	})
 
+	context("when buildpack.toml records the details of the SDK", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(filepath.Join(cnbDir, "buildpack.toml"), []byte(`api = "0.8"
+[[metadata.dependencies]]
+  id = "dotnet-sdk"
+  version = "some-version"
+  checksum = "sha256:some-sha"
+
+[[metadata.sdk-details]]
+  version = "other-version"
+  runtime-version = "other-runtime-version"
+
+[[metadata.sdk-details]]
+  version = "some-version"
+  runtime-version = "some-runtime-version"
+  aspnetcore-version = "some-aspnetcore-version"
+  vs-version = "some-vs-version"
+  csharp-version = "some-csharp-version"
+  fsharp-version = "some-fsharp-version"
+`), 0600)).To(Succeed())
+		})
+
+		it("records the bundled runtimes in the layer metadata and launch BOM", func() {
+			result, err := build(packit.BuildContext{
+				Plan: packit.BuildpackPlan{
+					Entries: []packit.BuildpackPlanEntry{
+						{Name: "dotnet-sdk"},
+					},
+				},
+				Layers:     packit.Layers{Path: layersDir},
+				CNBPath:    cnbDir,
+				WorkingDir: workingDir,
+				Stack:      "some-stack",
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Layers[0].Metadata["bundled-runtimes"]).To(Equal(map[string]interface{}{
+				"some-version": map[string]interface{}{
+					"dotnet-runtime":    "some-runtime-version",
+					"dotnet-aspnetcore": "some-aspnetcore-version",
+				},
+			}))
+
+			Expect(result.Build.BOM).To(HaveLen(1))
+			Expect(result.Launch.BOM).To(HaveLen(3))
+			Expect(result.Launch.BOM[1:]).To(Equal([]packit.BOMEntry{
+				{
+					Name:     "dotnet-runtime",
+					Metadata: paketosbom.BOMMetadata{Version: "some-runtime-version"},
+				},
+				{
+					Name:     "dotnet-aspnetcore",
+					Metadata: paketosbom.BOMMetadata{Version: "some-aspnetcore-version"},
+				},
+			}))
+		})
+
+		it("shows the details of the SDK", func() {
+			_, err := build(packit.BuildContext{
+				Plan: packit.BuildpackPlan{
+					Entries: []packit.BuildpackPlanEntry{
+						{Name: "dotnet-sdk"},
+					},
+				},
+				Layers:     packit.Layers{Path: layersDir},
+				CNBPath:    cnbDir,
+				WorkingDir: workingDir,
+				Stack:      "some-stack",
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(buffer.String()).To(ContainSubstring(".NET Core SDK some-version includes:"))
+			Expect(buffer.String()).To(ContainSubstring(".NET Runtime some-runtime-version"))
+			Expect(buffer.String()).To(ContainSubstring("ASP.NET Core Runtime some-aspnetcore-version"))
+			Expect(buffer.String()).To(ContainSubstring("C# some-csharp-version"))
+			Expect(buffer.String()).To(ContainSubstring("F# some-fsharp-version"))
+			Expect(buffer.String()).To(ContainSubstring("Visual Studio some-vs-version"))
+		})
+
+		context("when buildpack.toml cannot be parsed", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(cnbDir, "buildpack.toml"), []byte(`%%%`), 0600)).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{
+					Plan: packit.BuildpackPlan{
+						Entries: []packit.BuildpackPlanEntry{
+							{Name: "dotnet-sdk"},
+						},
+					},
+					Layers:     packit.Layers{Path: layersDir},
+					CNBPath:    cnbDir,
+					WorkingDir: workingDir,
+					Stack:      "some-stack",
+				})
+				Expect(err).To(MatchError(ContainSubstring("expected '.' or '=', but got '%' instead")))
+			})
+		})
+	})
+
+	context("when the version is resolved from global.json with roll-forward", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(filepath.Join(cnbDir, "buildpack.toml"), []byte(`api = "0.8"
+[[metadata.dependencies]]
+  id = "dotnet-sdk"
+  name = ".NET Core SDK"
+  stacks = ["some-stack"]
+  version = "9.0.203"
+  checksum = "sha512:sdk-9"
+`), 0600)).To(Succeed())
+
+			entries := []packit.BuildpackPlanEntry{
+				{
+					Name: "dotnet-sdk",
+					Metadata: map[string]interface{}{
+						"version":        "9.0.100",
+						"version-source": "global.json",
+						"roll-forward":   "minor",
+					},
+				},
+				{
+					Name: "dotnet-sdk",
+					Metadata: map[string]interface{}{
+						"version":        "8.0.*",
+						"version-source": "app.csproj",
+					},
+				},
+			}
+			entryResolver.ResolveCall.Returns.BuildpackPlanEntry = entries[0]
+			entryResolver.ResolveCall.Returns.BuildpackPlanEntrySlice = entries
+
+			build = dotnetcoresdk.Build(
+				entryResolver,
+				dependencyManager,
+				sbomGenerator,
+				dotnetCLI,
+				scribe.NewEmitter(buffer).WithLevel("DEBUG"),
+				chronos.DefaultClock,
+			)
+		})
+
+		it("reports the constraints that were tried", func() {
+			result, err := build(packit.BuildContext{
+				Plan: packit.BuildpackPlan{
+					Entries: []packit.BuildpackPlanEntry{
+						{Name: "dotnet-sdk"},
+					},
+				},
+				Layers:     packit.Layers{Path: layersDir},
+				CNBPath:    cnbDir,
+				WorkingDir: workingDir,
+				Stack:      "some-stack",
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Layers[0].Metadata["resolution-report"]).To(MatchJSON(`{
+				"candidates": [
+					{"version-source": "global.json", "constraint": "9.0.100", "priority-rank": 3, "selected": true},
+					{"version-source": "app.csproj", "constraint": "8.0.*", "priority-rank": 4, "selected": false}
+				],
+				"resolutions": [
+					{
+						"version-source": "global.json",
+						"constraint": "9.0.100",
+						"roll-forward": "minor",
+						"constraints-tried": [">= 9.0.100, < 9.0.200", ">= 9.0.200, < 9.0.300"],
+						"version": "9.0.203"
+					}
+				]
+			}`))
+
+			Expect(buffer.String()).To(ContainSubstring("SDK resolution report:"))
+			Expect(buffer.String()).To(ContainSubstring(`Candidate "9.0.100" from "global.json" at priority rank 3 (selected)`))
+			Expect(buffer.String()).To(ContainSubstring(`Resolved "9.0.100" from "global.json" to 9.0.203`))
+			Expect(buffer.String()).To(ContainSubstring("Constraint 2: >= 9.0.200, < 9.0.300"))
+		})
+	})
+
+	context("when the .NET CLI settings are overridden", func() {
+		it.Before(func() {
+			Expect(os.Setenv("BP_DOTNET_CLI_TELEMETRY_OPTOUT", "false")).To(Succeed())
+			Expect(os.Setenv("BP_DOTNET_GENERATE_ASPNET_CERTIFICATE", "true")).To(Succeed())
+
+			entryResolver.MergeLayerTypesCall.Returns.Launch = false
+		})
+
+		it.After(func() {
+			Expect(os.Unsetenv("BP_DOTNET_CLI_TELEMETRY_OPTOUT")).To(Succeed())
+			Expect(os.Unsetenv("BP_DOTNET_GENERATE_ASPNET_CERTIFICATE")).To(Succeed())
+		})
+
+		it("uses the configured values for build only", func() {
+			result, err := build(packit.BuildContext{
+				Plan: packit.BuildpackPlan{
+					Entries: []packit.BuildpackPlanEntry{
+						{Name: "dotnet-sdk"},
+					},
+				},
+				Layers:     packit.Layers{Path: layersDir},
+				CNBPath:    cnbDir,
+				WorkingDir: workingDir,
+				Stack:      "some-stack",
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			layer := result.Layers[0]
+			Expect(layer.BuildEnv).To(HaveKeyWithValue("DOTNET_CLI_TELEMETRY_OPTOUT.default", "false"))
+			Expect(layer.BuildEnv).To(HaveKeyWithValue("DOTNET_GENERATE_ASPNET_CERTIFICATE.default", "true"))
+			Expect(layer.BuildEnv).To(HaveKeyWithValue("DOTNET_NOLOGO.default", "true"))
+			Expect(layer.LaunchEnv).To(BeEmpty())
+		})
+	})
+
 	context("when there is a dependency cache match", func() {
 		it.Before(func() {
 			err := os.WriteFile(filepath.Join(layersDir, "dotnet-core-sdk.toml"),
-				[]byte("[metadata]\ndependency-checksum = \"sha256:some-sha\"\n"), 0600)
+				[]byte("[metadata.dependency-checksums]\nsome-version = \"sha256:some-sha\"\n"), 0600)
 			Expect(err).NotTo(HaveOccurred())
 
 			entryResolver.MergeLayerTypesCall.Returns.Build = true
@@ -354,18 +600,115 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 		})
 	})
 
-	context("failure cases", func() {
-		context("when the dependency for the build plan entry cannot be resolved", func() {
+	context("when several plan entries share the highest priority version source", func() {
+		it.Before(func() {
+			entries := []packit.BuildpackPlanEntry{
+				{
+					Name: "dotnet-sdk",
+					Metadata: map[string]interface{}{
+						"version":        "9.0.*",
+						"version-source": "BP_DOTNET_SDK_VERSION",
+					},
+				},
+				{
+					Name: "dotnet-sdk",
+					Metadata: map[string]interface{}{
+						"version":        "8.0.*",
+						"version-source": "BP_DOTNET_SDK_VERSION",
+					},
+				},
+				{
+					Name: "dotnet-sdk",
+					Metadata: map[string]interface{}{
+						"version":        "10.0.*",
+						"version-source": "app.csproj",
+					},
+				},
+			}
+			entryResolver.ResolveCall.Returns.BuildpackPlanEntry = entries[0]
+			entryResolver.ResolveCall.Returns.BuildpackPlanEntrySlice = entries
+
+			dependencyManager.ResolveCall.Stub = func(_, _, version, _ string) (postal.Dependency, error) {
+				switch version {
+				case "8.0.*":
+					return postal.Dependency{ID: "dotnet-sdk", Version: "8.0.424", Checksum: "sha512:sdk-8"}, nil
+				case "9.0.*":
+					return postal.Dependency{ID: "dotnet-sdk", Version: "9.0.317", Checksum: "sha512:sdk-9"}, nil
+				}
+				return postal.Dependency{}, fmt.Errorf("unexpected version %s", version)
+			}
+
+			var delivered []string
+			dependencyManager.DeliverCall.Stub = func(dependency postal.Dependency, _, layerPath, _ string) error {
+				delivered = append(delivered, dependency.Version)
+				return os.WriteFile(filepath.Join(layerPath, "delivered"), []byte(strings.Join(delivered, ",")), os.ModePerm)
+			}
+		})
+
+		it("installs each SDK into the shared layer", func() {
+			result, err := build(packit.BuildContext{
+				BuildpackInfo: packit.BuildpackInfo{
+					Version:     "1.2.3",
+					SBOMFormats: []string{sbom.CycloneDXFormat},
+				},
+				Plan: packit.BuildpackPlan{
+					Entries: []packit.BuildpackPlanEntry{
+						{Name: "dotnet-sdk"},
+					},
+				},
+				Layers:     packit.Layers{Path: layersDir},
+				CNBPath:    cnbDir,
+				WorkingDir: workingDir,
+				Stack:      "some-stack",
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Layers).To(HaveLen(1))
+			layer := result.Layers[0]
+			Expect(layer.Metadata).To(HaveKeyWithValue("dependency-checksums", map[string]interface{}{
+				"8.0.424": "sha512:sdk-8",
+				"9.0.317": "sha512:sdk-9",
+			}))
+			Expect(layer.Metadata["resolution-report"]).To(MatchJSON(`{
+				"candidates": [
+					{"version-source": "BP_DOTNET_SDK_VERSION", "constraint": "9.0.*", "priority-rank": 0, "selected": true},
+					{"version-source": "BP_DOTNET_SDK_VERSION", "constraint": "8.0.*", "priority-rank": 0, "selected": true},
+					{"version-source": "app.csproj", "constraint": "10.0.*", "priority-rank": 4, "selected": false}
+				],
+				"resolutions": [
+					{"version-source": "BP_DOTNET_SDK_VERSION", "constraint": "9.0.*", "constraints-tried": ["9.0.*"], "version": "9.0.317"},
+					{"version-source": "BP_DOTNET_SDK_VERSION", "constraint": "8.0.*", "constraints-tried": ["8.0.*"], "version": "8.0.424"}
+				]
+			}`))
+
+			Expect(dependencyManager.DeliverCall.CallCount).To(Equal(2))
+			Expect(filepath.Join(layersDir, "dotnet-core-sdk", "delivered")).To(BeARegularFile())
+			content, err := os.ReadFile(filepath.Join(layersDir, "dotnet-core-sdk", "delivered"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(content)).To(Equal("8.0.424,9.0.317"))
+
+			Expect(dependencyManager.GenerateBillOfMaterialsCall.Receives.Dependencies).To(Equal([]postal.Dependency{
+				{ID: "dotnet-sdk", Version: "8.0.424", Checksum: "sha512:sdk-8"},
+				{ID: "dotnet-sdk", Version: "9.0.317", Checksum: "sha512:sdk-9"},
+			}))
+
+			Expect(sbomGenerator.GenerateCall.Receives.Dir).To(Equal(filepath.Join(layersDir, "dotnet-core-sdk")))
+			Expect(sbomGenerator.GenerateFromDependencyCall.CallCount).To(Equal(0))
+		})
+
+		context("when only some of the SDKs are cached", func() {
 			it.Before(func() {
-				dependencyManager.ResolveCall.Returns.Error = errors.New("some-resolution-error")
+				Expect(os.MkdirAll(filepath.Join(layersDir, "dotnet-core-sdk"), os.ModePerm)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(layersDir, "dotnet-core-sdk", "delivered"), []byte("8.0.424"), os.ModePerm)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(layersDir, "dotnet-core-sdk.toml"),
+					[]byte("[metadata.dependency-checksums]\n\"8.0.424\" = \"sha512:sdk-8\"\n"), 0600)).To(Succeed())
 			})
-			it("returns an error", func() {
+
+			it("installs only the missing SDKs", func() {
 				_, err := build(packit.BuildContext{
 					Plan: packit.BuildpackPlan{
 						Entries: []packit.BuildpackPlanEntry{
-							{
-								Name: "dotnet-sdk",
-							},
+							{Name: "dotnet-sdk"},
 						},
 					},
 					Layers:     packit.Layers{Path: layersDir},
@@ -373,30 +716,26 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 					WorkingDir: workingDir,
 					Stack:      "some-stack",
 				})
+				Expect(err).NotTo(HaveOccurred())
 
-				Expect(err).To(MatchError("some-resolution-error"))
+				Expect(dependencyManager.DeliverCall.CallCount).To(Equal(1))
+				Expect(dependencyManager.DeliverCall.Receives.Dependency.Version).To(Equal("9.0.317"))
 			})
 		})
 
-		context("when layer dir cannot be accessed", func() {
+		context("when an older SDK is added to a cached newer SDK", func() {
 			it.Before(func() {
-				Expect(os.Chmod(layersDir, 0000)).To(Succeed())
-			})
-
-			it.After(func() {
-				Expect(os.Chmod(layersDir, 0600)).To(Succeed())
+				Expect(os.MkdirAll(filepath.Join(layersDir, "dotnet-core-sdk"), os.ModePerm)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(layersDir, "dotnet-core-sdk", "delivered"), []byte("9.0.317"), os.ModePerm)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(layersDir, "dotnet-core-sdk.toml"),
+					[]byte("[metadata.dependency-checksums]\n\"9.0.317\" = \"sha512:sdk-9\"\n"), 0600)).To(Succeed())
 			})
 
-			it("returns an error", func() {
+			it("resets the layer and installs every SDK in ascending order", func() {
 				_, err := build(packit.BuildContext{
-					BuildpackInfo: packit.BuildpackInfo{
-						Version: "1.2.3",
-					},
 					Plan: packit.BuildpackPlan{
 						Entries: []packit.BuildpackPlanEntry{
-							{
-								Name: "dotnet-sdk",
-							},
+							{Name: "dotnet-sdk"},
 						},
 					},
 					Layers:     packit.Layers{Path: layersDir},
@@ -404,34 +743,26 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 					WorkingDir: workingDir,
 					Stack:      "some-stack",
 				})
+				Expect(err).NotTo(HaveOccurred())
 
-				Expect(err).To(MatchError(ContainSubstring("permission denied")))
+				Expect(dependencyManager.DeliverCall.CallCount).To(Equal(2))
+				content, err := os.ReadFile(filepath.Join(layersDir, "dotnet-core-sdk", "delivered"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(content)).To(Equal("8.0.424,9.0.317"))
 			})
 		})
 
-		context("when layer cannot be removed", func() {
-			var layerDir string
+		context("when a cached SDK is no longer required", func() {
 			it.Before(func() {
-				layerDir = filepath.Join(layersDir, "dotnet-core-sdk")
-				Expect(os.MkdirAll(filepath.Join(layerDir, "dotnet-core-sdk"), os.ModePerm)).To(Succeed())
-				Expect(os.Chmod(layerDir, 0500)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(layersDir, "dotnet-core-sdk.toml"),
+					[]byte("[metadata.dependency-checksums]\n\"7.0.410\" = \"sha512:sdk-7\"\n\"8.0.424\" = \"sha512:sdk-8\"\n"), 0600)).To(Succeed())
 			})
 
-			it.After(func() {
-				Expect(os.Chmod(layerDir, os.ModePerm)).To(Succeed())
-				Expect(os.RemoveAll(layerDir)).To(Succeed())
-			})
-
-			it("returns an error", func() {
+			it("resets the layer and installs every SDK", func() {
 				_, err := build(packit.BuildContext{
-					BuildpackInfo: packit.BuildpackInfo{
-						Version: "1.2.3",
-					},
 					Plan: packit.BuildpackPlan{
 						Entries: []packit.BuildpackPlanEntry{
-							{
-								Name: "dotnet-sdk",
-							},
+							{Name: "dotnet-sdk"},
 						},
 					},
 					Layers:     packit.Layers{Path: layersDir},
@@ -439,27 +770,58 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 					WorkingDir: workingDir,
 					Stack:      "some-stack",
 				})
+				Expect(err).NotTo(HaveOccurred())
 
-				Expect(err).To(MatchError(ContainSubstring("permission denied")))
+				Expect(dependencyManager.DeliverCall.CallCount).To(Equal(2))
 			})
 		})
+	})
 
-		context("when the dependency for the build plan entry cannot be resolved", func() {
+	context("when workloads are required", func() {
+		it.Before(func() {
+			Expect(os.Setenv("BP_DOTNET_WORKLOADS", "wasm-tools,aspire")).To(Succeed())
+		})
+
+		it.After(func() {
+			Expect(os.Unsetenv("BP_DOTNET_WORKLOADS")).To(Succeed())
+		})
+
+		it("installs the workloads into the SDK layer", func() {
+			result, err := build(packit.BuildContext{
+				Plan: packit.BuildpackPlan{
+					Entries: []packit.BuildpackPlanEntry{
+						{Name: "dotnet-sdk"},
+					},
+				},
+				Layers:     packit.Layers{Path: layersDir},
+				CNBPath:    cnbDir,
+				WorkingDir: workingDir,
+				Stack:      "some-stack",
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(dependencyManager.DeliverCall.CallCount).To(Equal(1))
+			Expect(dotnetCLI.ExecuteCall.CallCount).To(Equal(1))
+			Expect(dotnetCLI.ExecuteCall.Receives.DotnetRoot).To(Equal(filepath.Join(layersDir, "dotnet-core-sdk")))
+			Expect(dotnetCLI.ExecuteCall.Receives.WorkingDir).To(Equal(workingDir))
+			Expect(dotnetCLI.ExecuteCall.Receives.Args).To(Equal([]string{"workload", "install", "aspire", "wasm-tools"}))
+
+			layer := result.Layers[0]
+			Expect(layer.Metadata).To(HaveKeyWithValue("workloads", []string{"aspire", "wasm-tools"}))
+			Expect(buffer.String()).To(ContainSubstring("Installing workloads aspire, wasm-tools"))
+		})
+
+		context("when the cached layer has the same workloads", func() {
 			it.Before(func() {
-				dependencyManager.DeliverCall.Stub = func(postal.Dependency, string, string, string) error {
-					return errors.New("some-installation-error")
-				}
+				Expect(os.WriteFile(filepath.Join(layersDir, "dotnet-core-sdk.toml"),
+					[]byte("[metadata]\nworkloads = [\"aspire\", \"wasm-tools\"]\n[metadata.dependency-checksums]\nsome-version = \"sha256:some-sha\"\n"), 0600)).To(Succeed())
 			})
-			it("returns an error", func() {
+
+			it("reuses the cached layer", func() {
 				_, err := build(packit.BuildContext{
-					BuildpackInfo: packit.BuildpackInfo{
-						Version: "1.2.3",
-					},
 					Plan: packit.BuildpackPlan{
 						Entries: []packit.BuildpackPlanEntry{
-							{
-								Name: "dotnet-sdk",
-							},
+							{Name: "dotnet-sdk"},
 						},
 					},
 					Layers:     packit.Layers{Path: layersDir},
@@ -467,26 +829,26 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 					WorkingDir: workingDir,
 					Stack:      "some-stack",
 				})
+				Expect(err).NotTo(HaveOccurred())
 
-				Expect(err).To(MatchError("some-installation-error"))
+				Expect(dependencyManager.DeliverCall.CallCount).To(Equal(0))
+				Expect(dotnetCLI.ExecuteCall.CallCount).To(Equal(0))
 			})
 		})
 
-		context("when generating the SBOM returns an error", func() {
+		context("when the cached layer has different workloads", func() {
 			it.Before(func() {
-				sbomGenerator.GenerateFromDependencyCall.Returns.Error = errors.New("failed to generate SBOM")
+				Expect(os.MkdirAll(filepath.Join(layersDir, "dotnet-core-sdk"), os.ModePerm)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(layersDir, "dotnet-core-sdk", "stale"), nil, os.ModePerm)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(layersDir, "dotnet-core-sdk.toml"),
+					[]byte("[metadata]\nworkloads = [\"maui\"]\n[metadata.dependency-checksums]\nsome-version = \"sha256:some-sha\"\n"), 0600)).To(Succeed())
 			})
 
-			it("returns an error", func() {
+			it("resets the layer and installs the SDK and workloads again", func() {
 				_, err := build(packit.BuildContext{
-					BuildpackInfo: packit.BuildpackInfo{
-						Version: "1.2.3",
-					},
 					Plan: packit.BuildpackPlan{
 						Entries: []packit.BuildpackPlanEntry{
-							{
-								Name: "dotnet-sdk",
-							},
+							{Name: "dotnet-sdk"},
 						},
 					},
 					Layers:     packit.Layers{Path: layersDir},
@@ -494,16 +856,776 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 					WorkingDir: workingDir,
 					Stack:      "some-stack",
 				})
-				Expect(err).To(MatchError(ContainSubstring("failed to generate SBOM")))
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(filepath.Join(layersDir, "dotnet-core-sdk", "stale")).NotTo(BeAnExistingFile())
+				Expect(dependencyManager.DeliverCall.CallCount).To(Equal(1))
+				Expect(dotnetCLI.ExecuteCall.CallCount).To(Equal(1))
 			})
 		})
+	})
 
-		context("when formatting the SBOM returns an error", func() {
-			it("returns an error", func() {
-				_, err := build(packit.BuildContext{
-					BuildpackInfo: packit.BuildpackInfo{SBOMFormats: []string{"random-format"}},
-					Plan: packit.BuildpackPlan{
-						Entries: []packit.BuildpackPlanEntry{
+	context("when the application has NuGet restore inputs", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(filepath.Join(workingDir, "app.csproj"), []byte(`<Project Sdk="Microsoft.NET.Sdk" />`), 0644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(workingDir, "packages.lock.json"), []byte(`{"version": 1}`), 0644)).To(Succeed())
+		})
+
+		it("contributes a NuGet package cache layer", func() {
+			result, err := build(packit.BuildContext{
+				Plan: packit.BuildpackPlan{
+					Entries: []packit.BuildpackPlanEntry{
+						{Name: "dotnet-sdk"},
+					},
+				},
+				Layers:     packit.Layers{Path: layersDir},
+				CNBPath:    cnbDir,
+				WorkingDir: workingDir,
+				Stack:      "some-stack",
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Layers).To(HaveLen(2))
+			cacheLayer := result.Layers[1]
+			Expect(cacheLayer.Name).To(Equal("nuget-cache"))
+			Expect(cacheLayer.Build).To(BeTrue())
+			Expect(cacheLayer.Cache).To(BeTrue())
+			Expect(cacheLayer.Launch).To(BeFalse())
+			Expect(cacheLayer.BuildEnv).To(Equal(packit.Environment{
+				"NUGET_PACKAGES.override": filepath.Join(layersDir, "nuget-cache"),
+			}))
+			Expect(cacheLayer.Metadata).To(Equal(map[string]interface{}{
+				"input-checksums": map[string]interface{}{
+					"app.csproj":         checksumOf(filepath.Join(workingDir, "app.csproj")),
+					"packages.lock.json": checksumOf(filepath.Join(workingDir, "packages.lock.json")),
+				},
+			}))
+		})
+
+		context("when the restore inputs are unchanged", func() {
+			it.Before(func() {
+				Expect(os.MkdirAll(filepath.Join(layersDir, "nuget-cache", "newtonsoft.json"), os.ModePerm)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(layersDir, "nuget-cache.toml"), []byte(fmt.Sprintf(
+					"[metadata.input-checksums]\n\"app.csproj\" = %q\n\"packages.lock.json\" = %q\n",
+					checksumOf(filepath.Join(workingDir, "app.csproj")),
+					checksumOf(filepath.Join(workingDir, "packages.lock.json")),
+				)), 0600)).To(Succeed())
+			})
+
+			it("reuses the cached packages", func() {
+				_, err := build(packit.BuildContext{
+					Plan: packit.BuildpackPlan{
+						Entries: []packit.BuildpackPlanEntry{
+							{Name: "dotnet-sdk"},
+						},
+					},
+					Layers:     packit.Layers{Path: layersDir},
+					CNBPath:    cnbDir,
+					WorkingDir: workingDir,
+					Stack:      "some-stack",
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(filepath.Join(layersDir, "nuget-cache", "newtonsoft.json")).To(BeADirectory())
+			})
+		})
+
+		context("when a restore input changed", func() {
+			it.Before(func() {
+				Expect(os.MkdirAll(filepath.Join(layersDir, "nuget-cache", "newtonsoft.json"), os.ModePerm)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(layersDir, "nuget-cache.toml"), []byte(fmt.Sprintf(
+					"[metadata.input-checksums]\n\"app.csproj\" = %q\n\"packages.lock.json\" = \"some-old-checksum\"\n",
+					checksumOf(filepath.Join(workingDir, "app.csproj")),
+				)), 0600)).To(Succeed())
+			})
+
+			it("resets the cache", func() {
+				_, err := build(packit.BuildContext{
+					Plan: packit.BuildpackPlan{
+						Entries: []packit.BuildpackPlanEntry{
+							{Name: "dotnet-sdk"},
+						},
+					},
+					Layers:     packit.Layers{Path: layersDir},
+					CNBPath:    cnbDir,
+					WorkingDir: workingDir,
+					Stack:      "some-stack",
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(filepath.Join(layersDir, "nuget-cache", "newtonsoft.json")).NotTo(BeADirectory())
+			})
+		})
+	})
+
+	context("when global.json declares msbuild-sdks and BP_DOTNET_RESTORE_MSBUILD_SDKS is set", func() {
+		var (
+			feedDir         string
+			restoredProject string
+		)
+
+		it.Before(func() {
+			Expect(os.Setenv("BP_DOTNET_RESTORE_MSBUILD_SDKS", "true")).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(workingDir, "global.json"), []byte(`{"msbuild-sdks": {"Microsoft.Build.Traversal": "4.1.0"}}`), 0644)).To(Succeed())
+
+			// A local feed stand-in: restoring copies the package from the feed
+			// into the packages directory
+			feedDir = t.TempDir()
+			Expect(os.WriteFile(filepath.Join(feedDir, "microsoft.build.traversal.4.1.0.nupkg"), []byte("package"), 0644)).To(Succeed())
+
+			dotnetCLI.ExecuteCall.Stub = func(dotnetRoot, workingDir string, env []string, args ...string) error {
+				content, err := os.ReadFile(args[1])
+				Expect(err).NotTo(HaveOccurred())
+				restoredProject = string(content)
+
+				packageDir := filepath.Join(args[3], "microsoft.build.traversal", "4.1.0")
+				Expect(os.MkdirAll(packageDir, os.ModePerm)).To(Succeed())
+				nupkg, err := os.ReadFile(filepath.Join(feedDir, "microsoft.build.traversal.4.1.0.nupkg"))
+				Expect(err).NotTo(HaveOccurred())
+				return os.WriteFile(filepath.Join(packageDir, "microsoft.build.traversal.4.1.0.nupkg"), nupkg, 0644)
+			}
+		})
+
+		it.After(func() {
+			Expect(os.Unsetenv("BP_DOTNET_RESTORE_MSBUILD_SDKS")).To(Succeed())
+		})
+
+		it("restores the SDK packages into the NuGet package cache", func() {
+			result, err := build(packit.BuildContext{
+				Plan: packit.BuildpackPlan{
+					Entries: []packit.BuildpackPlanEntry{
+						{
+							Name: "dotnet-sdk",
+							Metadata: map[string]interface{}{
+								"msbuild-sdks": map[string]interface{}{
+									"Microsoft.Build.Traversal": "4.1.0",
+								},
+							},
+						},
+					},
+				},
+				Layers:     packit.Layers{Path: layersDir},
+				CNBPath:    cnbDir,
+				WorkingDir: workingDir,
+				Stack:      "some-stack",
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Layers).To(HaveLen(2))
+			Expect(result.Layers[1].Name).To(Equal("nuget-cache"))
+
+			Expect(dotnetCLI.ExecuteCall.CallCount).To(Equal(1))
+			Expect(dotnetCLI.ExecuteCall.Receives.DotnetRoot).To(Equal(filepath.Join(layersDir, "dotnet-core-sdk")))
+			Expect(dotnetCLI.ExecuteCall.Receives.Args[0]).To(Equal("restore"))
+			Expect(dotnetCLI.ExecuteCall.Receives.Args[2:]).To(Equal([]string{"--packages", filepath.Join(layersDir, "nuget-cache")}))
+			Expect(restoredProject).To(ContainSubstring(`<PackageDownload Include="Microsoft.Build.Traversal" Version="[4.1.0]" />`))
+
+			Expect(filepath.Join(layersDir, "nuget-cache", "microsoft.build.traversal", "4.1.0", "microsoft.build.traversal.4.1.0.nupkg")).To(BeAnExistingFile())
+			Expect(buffer.String()).To(ContainSubstring("Restoring MSBuild project SDKs Microsoft.Build.Traversal"))
+		})
+	})
+
+	context("when there is a nugetconfig service binding", func() {
+		var platformDir string
+
+		it.Before(func() {
+			platformDir = t.TempDir()
+			Expect(os.MkdirAll(filepath.Join(platformDir, "bindings", "private-feed"), os.ModePerm)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(platformDir, "bindings", "private-feed", "type"), []byte("nugetconfig"), 0600)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(platformDir, "bindings", "private-feed", "nuget.config"), []byte("<configuration>secret</configuration>"), 0600)).To(Succeed())
+		})
+
+		it("writes the NuGet.Config into a build-only layer", func() {
+			result, err := build(packit.BuildContext{
+				Plan: packit.BuildpackPlan{
+					Entries: []packit.BuildpackPlanEntry{
+						{Name: "dotnet-sdk"},
+					},
+				},
+				Platform:   packit.Platform{Path: platformDir},
+				Layers:     packit.Layers{Path: layersDir},
+				CNBPath:    cnbDir,
+				WorkingDir: workingDir,
+				Stack:      "some-stack",
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Layers).To(HaveLen(2))
+			configLayer := result.Layers[1]
+			Expect(configLayer.Name).To(Equal("nuget-config"))
+			Expect(configLayer.Build).To(BeTrue())
+			Expect(configLayer.Launch).To(BeFalse())
+			Expect(configLayer.Cache).To(BeFalse())
+			Expect(configLayer.SBOM).To(BeNil())
+			Expect(configLayer.Metadata).To(BeEmpty())
+			Expect(configLayer.LaunchEnv).To(BeEmpty())
+			Expect(configLayer.BuildEnv).To(Equal(packit.Environment{
+				"DOTNET_CLI_HOME.override": filepath.Join(layersDir, "nuget-config"),
+			}))
+
+			content, err := os.ReadFile(filepath.Join(layersDir, "nuget-config", ".nuget", "NuGet", "NuGet.Config"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(content)).To(Equal("<configuration>secret</configuration>"))
+
+			Expect(result.Launch.BOM).NotTo(ContainElement(HaveField("Name", "nuget-config")))
+		})
+
+		context("when workloads are required", func() {
+			it.Before(func() {
+				Expect(os.Setenv("BP_DOTNET_WORKLOADS", "wasm-tools")).To(Succeed())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_DOTNET_WORKLOADS")).To(Succeed())
+			})
+
+			it("installs the workloads with the NuGet.Config", func() {
+				_, err := build(packit.BuildContext{
+					Plan: packit.BuildpackPlan{
+						Entries: []packit.BuildpackPlanEntry{
+							{Name: "dotnet-sdk"},
+						},
+					},
+					Platform:   packit.Platform{Path: platformDir},
+					Layers:     packit.Layers{Path: layersDir},
+					CNBPath:    cnbDir,
+					WorkingDir: workingDir,
+					Stack:      "some-stack",
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(dotnetCLI.ExecuteCall.CallCount).To(Equal(1))
+				Expect(dotnetCLI.ExecuteCall.Receives.Args).To(Equal([]string{
+					"workload", "install", "wasm-tools",
+					"--configfile", filepath.Join(layersDir, "nuget-config", ".nuget", "NuGet", "NuGet.Config"),
+				}))
+			})
+		})
+
+		context("when the application has a local tool manifest", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "dotnet-tools.json"), []byte(`{
+					"version": 1,
+					"tools": {"dotnet-ef": {"version": "9.0.4", "commands": ["dotnet-ef"]}}
+				}`), 0644)).To(Succeed())
+			})
+
+			it("restores the tools with the NuGet.Config", func() {
+				_, err := build(packit.BuildContext{
+					Plan: packit.BuildpackPlan{
+						Entries: []packit.BuildpackPlanEntry{
+							{Name: "dotnet-sdk"},
+						},
+					},
+					Platform:   packit.Platform{Path: platformDir},
+					Layers:     packit.Layers{Path: layersDir},
+					CNBPath:    cnbDir,
+					WorkingDir: workingDir,
+					Stack:      "some-stack",
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(dotnetCLI.ExecuteCall.Receives.Args).To(ContainElements(
+					"--configfile", filepath.Join(layersDir, "nuget-config", ".nuget", "NuGet", "NuGet.Config"),
+				))
+			})
+		})
+
+		context("failure cases", func() {
+			context("when the binding has no nuget.config entry", func() {
+				it.Before(func() {
+					Expect(os.Remove(filepath.Join(platformDir, "bindings", "private-feed", "nuget.config"))).To(Succeed())
+				})
+
+				it("returns an error", func() {
+					_, err := build(packit.BuildContext{
+						Plan: packit.BuildpackPlan{
+							Entries: []packit.BuildpackPlanEntry{
+								{Name: "dotnet-sdk"},
+							},
+						},
+						Platform:   packit.Platform{Path: platformDir},
+						Layers:     packit.Layers{Path: layersDir},
+						CNBPath:    cnbDir,
+						WorkingDir: workingDir,
+						Stack:      "some-stack",
+					})
+					Expect(err).To(MatchError("binding 'private-feed' of type 'nugetconfig' does not contain a nuget.config entry"))
+				})
+			})
+
+			context("when there is more than one binding", func() {
+				it.Before(func() {
+					Expect(os.MkdirAll(filepath.Join(platformDir, "bindings", "other-feed"), os.ModePerm)).To(Succeed())
+					Expect(os.WriteFile(filepath.Join(platformDir, "bindings", "other-feed", "type"), []byte("nugetconfig"), 0600)).To(Succeed())
+				})
+
+				it("returns an error", func() {
+					_, err := build(packit.BuildContext{
+						Plan: packit.BuildpackPlan{
+							Entries: []packit.BuildpackPlanEntry{
+								{Name: "dotnet-sdk"},
+							},
+						},
+						Platform:   packit.Platform{Path: platformDir},
+						Layers:     packit.Layers{Path: layersDir},
+						CNBPath:    cnbDir,
+						WorkingDir: workingDir,
+						Stack:      "some-stack",
+					})
+					Expect(err).To(MatchError("found 2 bindings of type 'nugetconfig' but expected at most 1"))
+				})
+			})
+		})
+	})
+
+	context("when the application has a local tool manifest", func() {
+		var executions [][]string
+
+		it.Before(func() {
+			Expect(os.MkdirAll(filepath.Join(workingDir, ".config"), os.ModePerm)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(workingDir, ".config", "dotnet-tools.json"), []byte(`{
+				"version": 1,
+				"isRoot": true,
+				"tools": {
+					"dotnet-ef": {"version": "9.0.4", "commands": ["dotnet-ef"]},
+					"csharpier": {"version": "0.30.6", "commands": ["dotnet-csharpier"]}
+				}
+			}`), 0644)).To(Succeed())
+
+			executions = nil
+			dotnetCLI.ExecuteCall.Stub = func(dotnetRoot, workingDir string, env []string, args ...string) error {
+				executions = append(executions, args)
+				return nil
+			}
+		})
+
+		it("restores the tools into the NuGet package cache and puts shims on the PATH", func() {
+			result, err := build(packit.BuildContext{
+				Plan: packit.BuildpackPlan{
+					Entries: []packit.BuildpackPlanEntry{
+						{Name: "dotnet-sdk"},
+					},
+				},
+				Layers:     packit.Layers{Path: layersDir},
+				CNBPath:    cnbDir,
+				WorkingDir: workingDir,
+				Stack:      "some-stack",
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Layers).To(HaveLen(3))
+			Expect(result.Layers[1].Name).To(Equal("nuget-cache"))
+			toolsLayer := result.Layers[2]
+			Expect(toolsLayer.Name).To(Equal("dotnet-tools"))
+			Expect(toolsLayer.Build).To(BeTrue())
+			Expect(toolsLayer.Cache).To(BeTrue())
+			Expect(toolsLayer.Launch).To(BeFalse())
+			Expect(toolsLayer.BuildEnv).To(Equal(packit.Environment{
+				"PATH.prepend": filepath.Join(layersDir, "dotnet-tools", "bin"),
+				"PATH.delim":   string(os.PathListSeparator),
+			}))
+			Expect(toolsLayer.Metadata).To(HaveKey("manifest-checksum"))
+
+			Expect(dotnetCLI.ExecuteCall.Receives.DotnetRoot).To(Equal(filepath.Join(layersDir, "dotnet-core-sdk")))
+			Expect(dotnetCLI.ExecuteCall.Receives.WorkingDir).To(Equal(workingDir))
+			Expect(dotnetCLI.ExecuteCall.Receives.Env).To(Equal([]string{fmt.Sprintf("NUGET_PACKAGES=%s", filepath.Join(layersDir, "nuget-cache"))}))
+			Expect(executions).To(Equal([][]string{
+				{"tool", "restore", "--tool-manifest", filepath.Join(workingDir, ".config", "dotnet-tools.json")},
+			}))
+
+			content, err := os.ReadFile(filepath.Join(layersDir, "dotnet-tools", "bin", "dotnet-ef"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(content)).To(Equal("#!/bin/sh\nexec dotnet tool run dotnet-ef \"$@\"\n"))
+			Expect(filepath.Join(layersDir, "dotnet-tools", "bin", "dotnet-csharpier")).To(BeARegularFile())
+		})
+
+		context("when the tools layer was built from the same manifest", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(layersDir, "dotnet-tools.toml"),
+					[]byte(fmt.Sprintf("[metadata]\nmanifest-checksum = %q\n", checksumOf(filepath.Join(workingDir, ".config", "dotnet-tools.json")))), 0600)).To(Succeed())
+				executions = nil
+			})
+
+			it("restores the tools and reuses the cached layer", func() {
+				result, err := build(packit.BuildContext{
+					Plan: packit.BuildpackPlan{
+						Entries: []packit.BuildpackPlanEntry{
+							{Name: "dotnet-sdk"},
+						},
+					},
+					Layers:     packit.Layers{Path: layersDir},
+					CNBPath:    cnbDir,
+					WorkingDir: workingDir,
+					Stack:      "some-stack",
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(executions).To(Equal([][]string{
+					{"tool", "restore", "--tool-manifest", filepath.Join(workingDir, ".config", "dotnet-tools.json")},
+				}))
+				Expect(result.Layers).To(HaveLen(3))
+				Expect(result.Layers[2].BuildEnv).To(HaveKeyWithValue("PATH.prepend", filepath.Join(layersDir, "dotnet-tools", "bin")))
+				Expect(filepath.Join(layersDir, "dotnet-tools", "bin", "dotnet-ef")).NotTo(BeAnExistingFile())
+			})
+		})
+
+		context("when restoring the tools fails", func() {
+			it.Before(func() {
+				dotnetCLI.ExecuteCall.Stub = nil
+				dotnetCLI.ExecuteCall.Returns.Error = errors.New("failed to execute 'dotnet tool restore'")
+			})
+
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{
+					Plan: packit.BuildpackPlan{
+						Entries: []packit.BuildpackPlanEntry{
+							{Name: "dotnet-sdk"},
+						},
+					},
+					Layers:     packit.Layers{Path: layersDir},
+					CNBPath:    cnbDir,
+					WorkingDir: workingDir,
+					Stack:      "some-stack",
+				})
+				Expect(err).To(MatchError("failed to execute 'dotnet tool restore'"))
+			})
+		})
+	})
+
+	context("when global.json lists an sdk path containing a matching SDK", func() {
+		var sdkRoot string
+
+		it.Before(func() {
+			sdkRoot = filepath.Join(workingDir, ".dotnet")
+			Expect(os.MkdirAll(filepath.Join(sdkRoot, "sdk", "9.0.203"), os.ModePerm)).To(Succeed())
+
+			entryResolver.ResolveCall.Returns.BuildpackPlanEntry = packit.BuildpackPlanEntry{
+				Name: "dotnet-sdk",
+				Metadata: map[string]interface{}{
+					"version":          "9.0.200",
+					"version-source":   "global.json",
+					"roll-forward":     "latestPatch",
+					"sdk-paths":        []interface{}{sdkRoot, "$host$"},
+					"global-json-path": filepath.Join(workingDir, "global.json"),
+				},
+			}
+		})
+
+		it("uses the local SDK instead of installing one", func() {
+			result, err := build(packit.BuildContext{
+				BuildpackInfo: packit.BuildpackInfo{
+					Version: "1.2.3",
+				},
+				Plan: packit.BuildpackPlan{
+					Entries: []packit.BuildpackPlanEntry{
+						{Name: "dotnet-sdk"},
+					},
+				},
+				Layers:     packit.Layers{Path: layersDir},
+				CNBPath:    cnbDir,
+				WorkingDir: workingDir,
+				Stack:      "some-stack",
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Layers).To(HaveLen(1))
+			layer := result.Layers[0]
+			Expect(layer.Metadata).To(Equal(map[string]interface{}{
+				"sdk-path":    sdkRoot,
+				"sdk-version": "9.0.203",
+			}))
+			Expect(layer.BuildEnv).To(HaveKeyWithValue("PATH.prepend", sdkRoot))
+			Expect(layer.BuildEnv).To(HaveKeyWithValue("DOTNET_ROOT.override", sdkRoot))
+
+			Expect(dependencyManager.DeliverCall.CallCount).To(Equal(0))
+			Expect(buffer.String()).To(ContainSubstring("Using global.json at %s", filepath.Join(workingDir, "global.json")))
+			Expect(buffer.String()).To(ContainSubstring("Using .NET Core SDK 9.0.203 from %s", sdkRoot))
+			Expect(buffer.String()).To(ContainSubstring("Skipping the end-of-life check, resolution report and workload installation for the local SDK"))
+		})
+
+		context("when the sdk paths are outside the application directory", func() {
+			var outsideRoot string
+
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(cnbDir, "buildpack.toml"), []byte(`api = "0.8"
+[[metadata.dependencies]]
+  id = "dotnet-sdk"
+  stacks = ["some-stack"]
+  version = "9.0.203"
+`), 0600)).To(Succeed())
+
+				outsideRoot = t.TempDir()
+				Expect(os.MkdirAll(filepath.Join(outsideRoot, "sdk", "9.0.203"), os.ModePerm)).To(Succeed())
+
+				escapingRoot := filepath.Join(workingDir, "..", filepath.Base(outsideRoot))
+				Expect(os.Symlink(outsideRoot, filepath.Join(workingDir, "linked-sdk"))).To(Succeed())
+
+				entryResolver.ResolveCall.Returns.BuildpackPlanEntry.Metadata["sdk-paths"] = []interface{}{outsideRoot, escapingRoot, filepath.Join(workingDir, "linked-sdk"), "$host$"}
+			})
+
+			it("ignores them and installs the SDK", func() {
+				result, err := build(packit.BuildContext{
+					Plan: packit.BuildpackPlan{
+						Entries: []packit.BuildpackPlanEntry{
+							{Name: "dotnet-sdk"},
+						},
+					},
+					Layers:     packit.Layers{Path: layersDir},
+					CNBPath:    cnbDir,
+					WorkingDir: workingDir,
+					Stack:      "some-stack",
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Layers[0].Metadata).NotTo(HaveKey("sdk-path"))
+				Expect(dependencyManager.DeliverCall.CallCount).To(Equal(1))
+				Expect(dependencyManager.DeliverCall.Receives.Dependency.Version).To(Equal("9.0.203"))
+				Expect(buffer.String()).To(ContainSubstring("Ignoring sdk path %s outside the application directory", outsideRoot))
+				Expect(buffer.String()).To(ContainSubstring("Ignoring sdk path %s outside the application directory", filepath.Join(workingDir, "linked-sdk")))
+			})
+		})
+	})
+
+	context("failure cases", func() {
+		context("when the selected SDK is past end of life and BP_DOTNET_SDK_FAIL_ON_EOL is set", func() {
+			it.Before(func() {
+				Expect(os.Setenv("BP_DOTNET_SDK_FAIL_ON_EOL", "true")).To(Succeed())
+				dependencyManager.ResolveCall.Returns.Dependency.DeprecationDate = time.Date(2020, time.May, 12, 0, 0, 0, 0, time.UTC)
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_DOTNET_SDK_FAIL_ON_EOL")).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{
+					Plan: packit.BuildpackPlan{
+						Entries: []packit.BuildpackPlanEntry{
+							{Name: "dotnet-sdk"},
+						},
+					},
+					Layers:     packit.Layers{Path: layersDir},
+					CNBPath:    cnbDir,
+					WorkingDir: workingDir,
+					Stack:      "some-stack",
+				})
+				Expect(err).To(MatchError(ContainSubstring(".NET Core SDK some-version reached end of life on 2020-05-12")))
+				Expect(dependencyManager.DeliverCall.CallCount).To(Equal(0))
+			})
+		})
+
+		context("when global.json resolution fails and an errorMessage is set", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(cnbDir, "buildpack.toml"), []byte(`api = "0.8"
+[[metadata.dependencies]]
+  id = "dotnet-sdk"
+  stacks = ["some-stack"]
+  version = "8.0.416"
+`), 0600)).To(Succeed())
+
+				entryResolver.ResolveCall.Returns.BuildpackPlanEntry = packit.BuildpackPlanEntry{
+					Name: "dotnet-sdk",
+					Metadata: map[string]interface{}{
+						"version":        "9.0.200",
+						"version-source": "global.json",
+						"roll-forward":   "patch",
+						"error-message":  "Install the patched SDK with ./eng/install-sdk.sh",
+					},
+				}
+			})
+
+			it("includes the errorMessage in the returned error", func() {
+				_, err := build(packit.BuildContext{
+					Plan: packit.BuildpackPlan{
+						Entries: []packit.BuildpackPlanEntry{
+							{Name: "dotnet-sdk"},
+						},
+					},
+					Layers:     packit.Layers{Path: layersDir},
+					CNBPath:    cnbDir,
+					WorkingDir: workingDir,
+					Stack:      "some-stack",
+				})
+				Expect(err).To(MatchError(ContainSubstring("Install the patched SDK with ./eng/install-sdk.sh: failed to resolve version 9.0.200")))
+			})
+		})
+
+		context("when installing the workloads fails", func() {
+			it.Before(func() {
+				Expect(os.Setenv("BP_DOTNET_WORKLOADS", "maui")).To(Succeed())
+				dotnetCLI.ExecuteCall.Returns.Error = errors.New("failed to execute 'dotnet workload install maui'")
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_DOTNET_WORKLOADS")).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{
+					Plan: packit.BuildpackPlan{
+						Entries: []packit.BuildpackPlanEntry{
+							{Name: "dotnet-sdk"},
+						},
+					},
+					Layers:     packit.Layers{Path: layersDir},
+					CNBPath:    cnbDir,
+					WorkingDir: workingDir,
+					Stack:      "some-stack",
+				})
+				Expect(err).To(MatchError("failed to execute 'dotnet workload install maui'"))
+			})
+		})
+
+		context("when the dependency for the build plan entry cannot be resolved", func() {
+			it.Before(func() {
+				dependencyManager.ResolveCall.Returns.Error = errors.New("some-resolution-error")
+			})
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{
+					Plan: packit.BuildpackPlan{
+						Entries: []packit.BuildpackPlanEntry{
+							{
+								Name: "dotnet-sdk",
+							},
+						},
+					},
+					Layers:     packit.Layers{Path: layersDir},
+					CNBPath:    cnbDir,
+					WorkingDir: workingDir,
+					Stack:      "some-stack",
+				})
+
+				Expect(err).To(MatchError("some-resolution-error"))
+			})
+		})
+
+		context("when layer dir cannot be accessed", func() {
+			it.Before(func() {
+				Expect(os.Chmod(layersDir, 0000)).To(Succeed())
+			})
+
+			it.After(func() {
+				Expect(os.Chmod(layersDir, 0600)).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{
+					BuildpackInfo: packit.BuildpackInfo{
+						Version: "1.2.3",
+					},
+					Plan: packit.BuildpackPlan{
+						Entries: []packit.BuildpackPlanEntry{
+							{
+								Name: "dotnet-sdk",
+							},
+						},
+					},
+					Layers:     packit.Layers{Path: layersDir},
+					CNBPath:    cnbDir,
+					WorkingDir: workingDir,
+					Stack:      "some-stack",
+				})
+
+				Expect(err).To(MatchError(ContainSubstring("permission denied")))
+			})
+		})
+
+		context("when layer cannot be removed", func() {
+			var layerDir string
+			it.Before(func() {
+				layerDir = filepath.Join(layersDir, "dotnet-core-sdk")
+				Expect(os.MkdirAll(filepath.Join(layerDir, "dotnet-core-sdk"), os.ModePerm)).To(Succeed())
+				Expect(os.Chmod(layerDir, 0500)).To(Succeed())
+			})
+
+			it.After(func() {
+				Expect(os.Chmod(layerDir, os.ModePerm)).To(Succeed())
+				Expect(os.RemoveAll(layerDir)).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{
+					BuildpackInfo: packit.BuildpackInfo{
+						Version: "1.2.3",
+					},
+					Plan: packit.BuildpackPlan{
+						Entries: []packit.BuildpackPlanEntry{
+							{
+								Name: "dotnet-sdk",
+							},
+						},
+					},
+					Layers:     packit.Layers{Path: layersDir},
+					CNBPath:    cnbDir,
+					WorkingDir: workingDir,
+					Stack:      "some-stack",
+				})
+
+				Expect(err).To(MatchError(ContainSubstring("permission denied")))
+			})
+		})
+
+		context("when the dependency for the build plan entry cannot be resolved", func() {
+			it.Before(func() {
+				dependencyManager.DeliverCall.Stub = func(postal.Dependency, string, string, string) error {
+					return errors.New("some-installation-error")
+				}
+			})
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{
+					BuildpackInfo: packit.BuildpackInfo{
+						Version: "1.2.3",
+					},
+					Plan: packit.BuildpackPlan{
+						Entries: []packit.BuildpackPlanEntry{
+							{
+								Name: "dotnet-sdk",
+							},
+						},
+					},
+					Layers:     packit.Layers{Path: layersDir},
+					CNBPath:    cnbDir,
+					WorkingDir: workingDir,
+					Stack:      "some-stack",
+				})
+
+				Expect(err).To(MatchError("some-installation-error"))
+			})
+		})
+
+		context("when generating the SBOM returns an error", func() {
+			it.Before(func() {
+				sbomGenerator.GenerateFromDependencyCall.Returns.Error = errors.New("failed to generate SBOM")
+			})
+
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{
+					BuildpackInfo: packit.BuildpackInfo{
+						Version: "1.2.3",
+					},
+					Plan: packit.BuildpackPlan{
+						Entries: []packit.BuildpackPlanEntry{
+							{
+								Name: "dotnet-sdk",
+							},
+						},
+					},
+					Layers:     packit.Layers{Path: layersDir},
+					CNBPath:    cnbDir,
+					WorkingDir: workingDir,
+					Stack:      "some-stack",
+				})
+				Expect(err).To(MatchError(ContainSubstring("failed to generate SBOM")))
+			})
+		})
+
+		context("when formatting the SBOM returns an error", func() {
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{
+					BuildpackInfo: packit.BuildpackInfo{SBOMFormats: []string{"random-format"}},
+					Plan: packit.BuildpackPlan{
+						Entries: []packit.BuildpackPlanEntry{
 							{
 								Name: "dotnet-sdk",
 							},
@@ -519,3 +1641,12 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 		})
 	})
 }
+
+func checksumOf(path string) string {
+	checksum, err := fs.NewChecksumCalculator().Sum(path)
+	if err != nil {
+		panic(err)
+	}
+
+	return checksum
+}
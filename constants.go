@@ -7,7 +7,8 @@ var Priorities = []interface{}{
 	DeprecatedFrameworkVersion,
 	"buildpack.yml",
 	"global.json",
-	regexp.MustCompile(`.*\.(cs)|(fs)|(vb)proj`),
+	regexp.MustCompile(`.*\.(cs|fs|vb)proj$`),
+	DirectoryBuildProps,
 	"runtimeconfig.json",
 	"",
 }
@@ -16,4 +17,23 @@ const (
 	DotnetDependency           = "dotnet-sdk"
 	DotnetSdkVersion           = "BP_DOTNET_SDK_VERSION"
 	DeprecatedFrameworkVersion = "BP_DOTNET_FRAMEWORK_VERSION"
+	EOLWarningDays             = "BP_DOTNET_SDK_EOL_WARNING_DAYS"
+	FailOnEOL                  = "BP_DOTNET_SDK_FAIL_ON_EOL"
+	Workloads                  = "BP_DOTNET_WORKLOADS"
+	ProjectPath                = "BP_DOTNET_PROJECT_PATH"
+	GlobalJsonSearchAboveRoot  = "BP_DOTNET_GLOBAL_JSON_SEARCH_ABOVE_ROOT"
+	RestoreMsbuildSdks         = "BP_DOTNET_RESTORE_MSBUILD_SDKS"
 )
+
+// CliEnvironmentDefaults are the .NET CLI environment variables set on the SDK
+// layer. Each value can be overridden by the corresponding BP_DOTNET_* setting.
+var CliEnvironmentDefaults = []struct {
+	Name    string
+	Setting string
+	Default string
+}{
+	{Name: "DOTNET_CLI_TELEMETRY_OPTOUT", Setting: "BP_DOTNET_CLI_TELEMETRY_OPTOUT", Default: "true"},
+	{Name: "DOTNET_NOLOGO", Setting: "BP_DOTNET_NOLOGO", Default: "true"},
+	{Name: "DOTNET_SKIP_FIRST_TIME_EXPERIENCE", Setting: "BP_DOTNET_SKIP_FIRST_TIME_EXPERIENCE", Default: "true"},
+	{Name: "DOTNET_GENERATE_ASPNET_CERTIFICATE", Setting: "BP_DOTNET_GENERATE_ASPNET_CERTIFICATE", Default: "false"},
+}
@@ -2,6 +2,7 @@ package components
 
 import (
 	"fmt"
+	"io"
 	"net/http"
 	"strings"
 	"time"
@@ -12,7 +13,58 @@ import (
 	"github.com/paketo-buildpacks/packit/v2/cargo"
 )
 
+// MetadataGenerator generates the dependency metadata for an SDK release,
+// validating the hash of its archive according to its validation strategy.
+type MetadataGenerator struct {
+	strategy  ValidationStrategy
+	validated *ValidatedHashes
+	client    *http.Client
+	offline   bool
+}
+
+func NewMetadataGenerator() MetadataGenerator {
+	return MetadataGenerator{
+		strategy:  ValidationFull,
+		validated: NewValidatedHashes(),
+		client:    &http.Client{Timeout: 30 * time.Minute},
+	}
+}
+
+// WithValidation sets the strategy used to validate the hash of each archive.
+func (g MetadataGenerator) WithValidation(strategy ValidationStrategy) MetadataGenerator {
+	g.strategy = strategy
+	return g
+}
+
+// WithValidatedHashes sets the records of the archives that have already
+// been validated, which are not validated again.
+func (g MetadataGenerator) WithValidatedHashes(validated *ValidatedHashes) MetadataGenerator {
+	g.validated = validated
+	return g
+}
+
+// WithClient sets the HTTP client used to validate archives.
+func (g MetadataGenerator) WithClient(client *http.Client) MetadataGenerator {
+	g.client = client
+	return g
+}
+
+// WithOffline makes validation fail for archives that have not been validated
+// before, rather than making any requests, unless validation is skipped.
+func (g MetadataGenerator) WithOffline(offline bool) MetadataGenerator {
+	g.offline = offline
+	return g
+}
+
+// GenerateMetadata generates the dependency metadata for the release,
+// downloading its archive to validate the hash. versionology.Dependency has no
+// room for the details returned by SdkRelease.ExtraMetadata, so those are
+// added to the metadata file afterwards by AddReleaseMetadata.
 func GenerateMetadata(version versionology.VersionFetcher, platform retrieve.Platform) ([]versionology.Dependency, error) {
+	return NewMetadataGenerator().Generate(version, platform)
+}
+
+func (g MetadataGenerator) Generate(version versionology.VersionFetcher, platform retrieve.Platform) ([]versionology.Dependency, error) {
 	sdkRelease := version.(SdkRelease)
 
 	arch := platform.Arch
@@ -33,22 +85,10 @@ func GenerateMetadata(version versionology.VersionFetcher, platform retrieve.Pla
 		return nil, fmt.Errorf("could not find release file for %s", rid)
 	}
 
-	// Validate the artifact
-	response, err := http.Get(archive.URL)
+	err := g.validate(archive)
 	if err != nil {
 		return nil, err
 	}
-	defer response.Body.Close()
-
-	vr := cargo.NewValidatedReader(response.Body, fmt.Sprintf("sha512:%s", archive.Hash))
-	valid, err := vr.Valid()
-	if err != nil {
-		return nil, err
-	}
-
-	if !valid {
-		return nil, fmt.Errorf("the given checksum of the artifact does not match with downloaded artifact")
-	}
 
 	var depDate *time.Time
 	if sdkRelease.EOLDate != "" {
@@ -91,3 +131,91 @@ func GenerateMetadata(version versionology.VersionFetcher, platform retrieve.Pla
 
 	return []versionology.Dependency{dependency}, nil
 }
+
+// validate checks the hash of the archive with the validation strategy,
+// unless the archive has already been validated with the same hash by a
+// strategy at least as strong.
+func (g MetadataGenerator) validate(archive ReleaseFile) error {
+	if g.strategy == ValidationSkip {
+		return nil
+	}
+
+	if g.validated != nil && g.validated.Contains(archive.URL, archive.Hash, g.strategy) {
+		return nil
+	}
+
+	if g.offline {
+		return fmt.Errorf("cannot validate %s in offline mode: it has not been validated before", archive.URL)
+	}
+
+	var err error
+	switch g.strategy {
+	case ValidationTrustedHash:
+		err = g.validatePublishedHash(archive)
+	default:
+		err = g.validateArchive(archive)
+	}
+	if err != nil {
+		return err
+	}
+
+	if g.validated != nil {
+		return g.validated.Add(archive.URL, archive.Hash, g.strategy)
+	}
+
+	return nil
+}
+
+// validateArchive downloads the archive and checks its hash.
+func (g MetadataGenerator) validateArchive(archive ReleaseFile) error {
+	response, err := g.client.Get(archive.URL)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if !(response.StatusCode >= 200 && response.StatusCode < 300) {
+		return fmt.Errorf("received a non 200 status code from %s: status code %d received", archive.URL, response.StatusCode)
+	}
+
+	vr := cargo.NewValidatedReader(response.Body, fmt.Sprintf("sha512:%s", archive.Hash))
+	valid, err := vr.Valid()
+	if err != nil {
+		return err
+	}
+
+	if !valid {
+		return fmt.Errorf("the given checksum of the artifact does not match with downloaded artifact")
+	}
+
+	return nil
+}
+
+// validatePublishedHash checks the hash from the release metadata against the
+// .sha512 file published next to the archive, which holds the hash optionally
+// followed by the file name.
+func (g MetadataGenerator) validatePublishedHash(archive ReleaseFile) error {
+	uri := archive.URL + ".sha512"
+
+	response, err := g.client.Get(uri)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if !(response.StatusCode >= 200 && response.StatusCode < 300) {
+		return fmt.Errorf("received a non 200 status code from %s: status code %d received", uri, response.StatusCode)
+	}
+
+	content, err := io.ReadAll(io.LimitReader(response.Body, 4096))
+	if err != nil {
+		return err
+	}
+
+	fields := strings.Fields(string(content))
+	if len(fields) == 0 || !strings.EqualFold(fields[0], archive.Hash) {
+		return fmt.Errorf("the checksum of %s in the release metadata does not match the published checksum in %s", archive.URL, uri)
+	}
+
+	return nil
+}
@@ -4,9 +4,15 @@ import (
 	"archive/tar"
 	"bytes"
 	"compress/gzip"
+	"crypto/sha512"
+	"encoding/hex"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -193,4 +199,257 @@ func testDependency(t *testing.T, context spec.G, it spec.S) {
 			})
 		})
 	})
+
+	context("MetadataGenerator", func() {
+		var (
+			server    *httptest.Server
+			generator components.MetadataGenerator
+			release   components.SdkRelease
+			platform  retrieve.Platform
+
+			mutex    sync.Mutex
+			requests map[string]int
+			sidecar  string
+		)
+
+		archive := []byte("some-archive-content")
+		sum := sha512.Sum512(archive)
+		hash := hex.EncodeToString(sum[:])
+
+		requestCount := func(path string) int {
+			mutex.Lock()
+			defer mutex.Unlock()
+			return requests[path]
+		}
+
+		it.Before(func() {
+			requests = map[string]int{}
+			sidecar = fmt.Sprintf("%s  dotnet-sdk-linux-x64.tar.gz\n", strings.ToUpper(hash))
+
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				mutex.Lock()
+				requests[req.URL.Path]++
+				content := sidecar
+				mutex.Unlock()
+
+				switch req.URL.Path {
+				case "/dotnet-sdk-linux-x64.tar.gz":
+					_, _ = w.Write(archive)
+				case "/dotnet-sdk-linux-x64.tar.gz.sha512":
+					if content == "" {
+						w.WriteHeader(http.StatusNotFound)
+						return
+					}
+					fmt.Fprint(w, content)
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+
+			release = components.SdkRelease{
+				SemVer:         semver.MustParse("8.0.100"),
+				ReleaseVersion: "8.0.100",
+				Files: []components.ReleaseFile{
+					{
+						Name: "dotnet-sdk-linux-x64.tar.gz",
+						Rid:  "linux-x64",
+						URL:  fmt.Sprintf("%s/dotnet-sdk-linux-x64.tar.gz", server.URL),
+						Hash: hash,
+					},
+				},
+			}
+			platform = retrieve.Platform{OS: "linux", Arch: "amd64"}
+
+			generator = components.NewMetadataGenerator()
+		})
+
+		it.After(func() {
+			server.Close()
+		})
+
+		context("with full validation", func() {
+			it("downloads each archive once", func() {
+				validated := components.NewValidatedHashes()
+				generator = generator.WithValidatedHashes(validated)
+
+				dependencies, err := generator.Generate(release, platform)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(dependencies[0].Checksum).To(Equal(fmt.Sprintf("sha512:%s", hash)))
+
+				_, err = generator.Generate(release, platform)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(requestCount("/dotnet-sdk-linux-x64.tar.gz")).To(Equal(1))
+				Expect(validated.Contains(release.Files[0].URL, hash, components.ValidationFull)).To(BeTrue())
+			})
+
+			context("when the validated hashes are persisted", func() {
+				var path string
+
+				it.Before(func() {
+					path = filepath.Join(t.TempDir(), "cache", "validated-hashes.json")
+				})
+
+				it("does not download the archive again on a later run", func() {
+					validated, err := components.LoadValidatedHashes(path)
+					Expect(err).NotTo(HaveOccurred())
+
+					_, err = generator.WithValidatedHashes(validated).Generate(release, platform)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(path).To(BeAnExistingFile())
+
+					validated, err = components.LoadValidatedHashes(path)
+					Expect(err).NotTo(HaveOccurred())
+
+					_, err = generator.WithValidatedHashes(validated).Generate(release, platform)
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(requestCount("/dotnet-sdk-linux-x64.tar.gz")).To(Equal(1))
+				})
+
+				it("downloads the archive again when its hash has changed", func() {
+					Expect(os.MkdirAll(filepath.Dir(path), os.ModePerm)).To(Succeed())
+					Expect(os.WriteFile(path, []byte(fmt.Sprintf(`{%q: {"hash": "other-hash", "strategy": "full"}}`, release.Files[0].URL)), 0600)).To(Succeed())
+
+					validated, err := components.LoadValidatedHashes(path)
+					Expect(err).NotTo(HaveOccurred())
+
+					_, err = generator.WithValidatedHashes(validated).Generate(release, platform)
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(requestCount("/dotnet-sdk-linux-x64.tar.gz")).To(Equal(1))
+				})
+			})
+
+			context("when the archive has only been validated with trusted-hash validation", func() {
+				it("downloads the archive", func() {
+					validated := components.NewValidatedHashes()
+					Expect(validated.Add(release.Files[0].URL, hash, components.ValidationTrustedHash)).To(Succeed())
+
+					_, err := generator.WithValidatedHashes(validated).Generate(release, platform)
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(requestCount("/dotnet-sdk-linux-x64.tar.gz")).To(Equal(1))
+					Expect(validated.Contains(release.Files[0].URL, hash, components.ValidationFull)).To(BeTrue())
+				})
+			})
+
+			context("failure cases", func() {
+				context("when the archive cannot be downloaded", func() {
+					it.Before(func() {
+						release.Files[0].URL = fmt.Sprintf("%s/missing.tar.gz", server.URL)
+					})
+
+					it("returns an error", func() {
+						_, err := generator.Generate(release, platform)
+						Expect(err).To(MatchError(fmt.Sprintf("received a non 200 status code from %s/missing.tar.gz: status code 404 received", server.URL)))
+					})
+				})
+
+				context("when the archive does not match its hash", func() {
+					it.Before(func() {
+						release.Files[0].Hash = strings.Repeat("0", 128)
+					})
+
+					it("returns an error and does not record the hash", func() {
+						validated := components.NewValidatedHashes()
+
+						_, err := generator.WithValidatedHashes(validated).Generate(release, platform)
+						Expect(err).To(MatchError("the given checksum of the artifact does not match with downloaded artifact"))
+						Expect(validated.Contains(release.Files[0].URL, release.Files[0].Hash, components.ValidationFull)).To(BeFalse())
+					})
+				})
+			})
+		})
+
+		context("when validation is skipped", func() {
+			it.Before(func() {
+				generator = generator.WithValidation(components.ValidationSkip)
+			})
+
+			it("makes no requests", func() {
+				dependencies, err := generator.Generate(release, platform)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(dependencies[0].Checksum).To(Equal(fmt.Sprintf("sha512:%s", hash)))
+
+				Expect(requestCount("/dotnet-sdk-linux-x64.tar.gz")).To(Equal(0))
+				Expect(requestCount("/dotnet-sdk-linux-x64.tar.gz.sha512")).To(Equal(0))
+			})
+		})
+
+		context("with trusted-hash validation", func() {
+			it.Before(func() {
+				generator = generator.WithValidation(components.ValidationTrustedHash)
+			})
+
+			it("checks the hash against the published .sha512 file without downloading the archive", func() {
+				dependencies, err := generator.Generate(release, platform)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(dependencies[0].Checksum).To(Equal(fmt.Sprintf("sha512:%s", hash)))
+
+				Expect(requestCount("/dotnet-sdk-linux-x64.tar.gz")).To(Equal(0))
+				Expect(requestCount("/dotnet-sdk-linux-x64.tar.gz.sha512")).To(Equal(1))
+			})
+
+			context("when the archive has been validated with full validation", func() {
+				it("makes no requests", func() {
+					validated := components.NewValidatedHashes()
+					Expect(validated.Add(release.Files[0].URL, hash, components.ValidationFull)).To(Succeed())
+
+					_, err := generator.WithValidatedHashes(validated).Generate(release, platform)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(requests).To(BeEmpty())
+				})
+			})
+
+			context("failure cases", func() {
+				context("when the published hash does not match", func() {
+					it.Before(func() {
+						sidecar = strings.Repeat("0", 128)
+					})
+
+					it("returns an error", func() {
+						_, err := generator.Generate(release, platform)
+						Expect(err).To(MatchError(fmt.Sprintf("the checksum of %[1]s/dotnet-sdk-linux-x64.tar.gz in the release metadata does not match the published checksum in %[1]s/dotnet-sdk-linux-x64.tar.gz.sha512", server.URL)))
+					})
+				})
+
+				context("when there is no published hash", func() {
+					it.Before(func() {
+						sidecar = ""
+					})
+
+					it("returns an error", func() {
+						_, err := generator.Generate(release, platform)
+						Expect(err).To(MatchError(fmt.Sprintf("received a non 200 status code from %s/dotnet-sdk-linux-x64.tar.gz.sha512: status code 404 received", server.URL)))
+					})
+				})
+			})
+		})
+
+		context("when offline", func() {
+			it.Before(func() {
+				generator = generator.WithOffline(true)
+			})
+
+			it("accepts archives that have been validated before without making any requests", func() {
+				validated := components.NewValidatedHashes()
+				Expect(validated.Add(release.Files[0].URL, hash, components.ValidationFull)).To(Succeed())
+
+				_, err := generator.WithValidatedHashes(validated).Generate(release, platform)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(requests).To(BeEmpty())
+			})
+
+			context("failure cases", func() {
+				context("when the archive has not been validated before", func() {
+					it("returns an error", func() {
+						_, err := generator.Generate(release, platform)
+						Expect(err).To(MatchError(fmt.Sprintf("cannot validate %s/dotnet-sdk-linux-x64.tar.gz in offline mode: it has not been validated before", server.URL)))
+						Expect(requests).To(BeEmpty())
+					})
+				})
+			})
+		})
+	})
 }
@@ -10,6 +10,9 @@ import (
 func TestUnit(t *testing.T) {
 	suite := spec.New("dotnet-core-sdk-retrieval", spec.Report(report.Terminal{}), spec.Parallel())
 	suite("Dependency", testDependency)
+	suite("ReleaseMetadata", testReleaseMetadata)
 	suite("Releases", testReleases)
+	suite("SdkDetails", testSdkDetails)
+	suite("Validation", testValidation)
 	suite.Run(t)
 }
@@ -0,0 +1,59 @@
+package components
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/paketo-buildpacks/libdependency/versionology"
+)
+
+// AddReleaseMetadata adds the release details that do not fit into
+// cargo.ConfigMetadataDependency, such as the versions of the runtimes bundled
+// with each SDK and the language versions it supports, to the dependencies in
+// the metadata file at path. Entries are matched to releases by version.
+func AddReleaseMetadata(path string, releases versionology.VersionFetcherArray) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read metadata file: %w", err)
+	}
+
+	var dependencies []map[string]interface{}
+	err = json.Unmarshal(content, &dependencies)
+	if err != nil {
+		return fmt.Errorf("failed to parse metadata file: %w", err)
+	}
+
+	sdkReleases := map[string]SdkRelease{}
+	for _, release := range releases {
+		if sdkRelease, ok := release.(SdkRelease); ok {
+			sdkReleases[sdkRelease.SemVer.String()] = sdkRelease
+		}
+	}
+
+	for _, dependency := range dependencies {
+		version, _ := dependency["version"].(string)
+		sdkRelease, ok := sdkReleases[version]
+		if !ok {
+			continue
+		}
+
+		for key, value := range sdkRelease.ExtraMetadata() {
+			dependency[key] = value
+		}
+	}
+
+	// The metadata is consumed by a workflow, which expects JSON without
+	// whitespace
+	content, err = json.Marshal(dependencies)
+	if err != nil {
+		return fmt.Errorf("failed to serialize metadata file: %w", err)
+	}
+
+	err = os.WriteFile(path, content, os.ModePerm)
+	if err != nil {
+		return fmt.Errorf("failed to write metadata file: %w", err)
+	}
+
+	return nil
+}
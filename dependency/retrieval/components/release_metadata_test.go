@@ -0,0 +1,73 @@
+package components_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/paketo-buildpacks/dotnet-core-sdk/dependency/retrieval/components"
+	"github.com/paketo-buildpacks/libdependency/versionology"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testReleaseMetadata(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		path string
+	)
+
+	it.Before(func() {
+		path = filepath.Join(t.TempDir(), "metadata.json")
+		Expect(os.WriteFile(path, []byte(`[{"id":"dotnet-sdk","version":"6.0.401","arch":"amd64"},{"id":"dotnet-sdk","version":"6.0.401","arch":"arm64"},{"id":"dotnet-sdk","version":"6.0.400"}]`), 0600)).To(Succeed())
+	})
+
+	context("AddReleaseMetadata", func() {
+		it("adds the bundled runtime and language versions to the matching dependencies", func() {
+			err := components.AddReleaseMetadata(path, versionology.VersionFetcherArray{
+				components.SdkRelease{
+					SemVer:            semver.MustParse("6.0.401"),
+					ReleaseVersion:    "6.0.401",
+					RuntimeVersion:    "6.0.9",
+					AspNetCoreVersion: "6.0.9",
+					VSVersion:         "17.3.4",
+					CSharpVersion:     "10.0",
+					FSharpVersion:     "6.0",
+				},
+				components.SdkRelease{
+					SemVer:         semver.MustParse("6.0.300"),
+					ReleaseVersion: "6.0.300",
+					RuntimeVersion: "6.0.5",
+				},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			content, err := os.ReadFile(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(content)).To(Equal(`[{"arch":"amd64","aspnetcore-version":"6.0.9","csharp-version":"10.0","fsharp-version":"6.0","id":"dotnet-sdk","runtime-version":"6.0.9","version":"6.0.401","vs-version":"17.3.4"},{"arch":"arm64","aspnetcore-version":"6.0.9","csharp-version":"10.0","fsharp-version":"6.0","id":"dotnet-sdk","runtime-version":"6.0.9","version":"6.0.401","vs-version":"17.3.4"},{"id":"dotnet-sdk","version":"6.0.400"}]`))
+		})
+
+		context("failure cases", func() {
+			context("when the metadata file does not exist", func() {
+				it("returns an error", func() {
+					err := components.AddReleaseMetadata(filepath.Join(t.TempDir(), "missing.json"), nil)
+					Expect(err).To(MatchError(ContainSubstring("failed to read metadata file")))
+				})
+			})
+
+			context("when the metadata file cannot be parsed", func() {
+				it.Before(func() {
+					Expect(os.WriteFile(path, []byte(`???`), 0600)).To(Succeed())
+				})
+
+				it("returns an error", func() {
+					err := components.AddReleaseMetadata(path, nil)
+					Expect(err).To(MatchError(ContainSubstring("failed to parse metadata file")))
+				})
+			})
+		})
+	})
+}
@@ -1,9 +1,19 @@
 package components
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
 
 	"github.com/Masterminds/semver/v3"
 	"github.com/paketo-buildpacks/libdependency/versionology"
@@ -14,6 +24,24 @@ type SdkRelease struct {
 	EOLDate        string
 	ReleaseVersion string
 	Files          []ReleaseFile
+
+	// RuntimeVersion and AspNetCoreVersion are the versions of the
+	// Microsoft.NETCore.App and Microsoft.AspNetCore.App runtimes bundled with
+	// the SDK
+	RuntimeVersion    string
+	AspNetCoreVersion string
+
+	// VSVersion, CSharpVersion and FSharpVersion are the Visual Studio version
+	// the SDK ships with and the C# and F# language versions it supports
+	VSVersion     string
+	CSharpVersion string
+	FSharpVersion string
+
+	// ChannelVersion, SupportPhase and ReleaseType describe the channel of the
+	// release in releases-index.json, e.g. "8.0", "active" and "lts"
+	ChannelVersion string
+	SupportPhase   string
+	ReleaseType    string
 }
 
 type ReleaseFile struct {
@@ -27,13 +55,71 @@ func (sdkRelease SdkRelease) Version() *semver.Version {
 	return sdkRelease.SemVer
 }
 
+// ExtraMetadata returns the known details of the release that are recorded
+// in the dependency metadata in addition to the cargo fields.
+func (sdkRelease SdkRelease) ExtraMetadata() map[string]string {
+	metadata := map[string]string{}
+	for key, value := range map[string]string{
+		"runtime-version":    sdkRelease.RuntimeVersion,
+		"aspnetcore-version": sdkRelease.AspNetCoreVersion,
+		"vs-version":         sdkRelease.VSVersion,
+		"csharp-version":     sdkRelease.CSharpVersion,
+		"fsharp-version":     sdkRelease.FSharpVersion,
+	} {
+		if value != "" {
+			metadata[key] = value
+		}
+	}
+
+	return metadata
+}
+
 type Fetcher struct {
 	releaseIndex string
+	client       *http.Client
+
+	// concurrency is the maximum number of release pages fetched at once
+	concurrency int
+
+	// attempts is the maximum number of times a request is made when it fails
+	// with a transient error, waiting backoff before the first retry and twice
+	// as long before each subsequent one
+	attempts int
+	backoff  time.Duration
+
+	// cacheDir, when set, holds the responses of previous runs, which are
+	// revalidated with conditional requests or, when offline, used as is
+	cacheDir string
+	offline  bool
+
+	// supportPhases and channels, when set, limit the channels that releases
+	// are fetched from, and latestPerFeatureBand, when positive, limits the
+	// number of releases kept in each feature band
+	supportPhases        []string
+	channels             []string
+	latestPerFeatureBand int
 }
 
 func NewFetcher() Fetcher {
 	return Fetcher{
 		releaseIndex: "https://dotnetcli.blob.core.windows.net/dotnet/release-metadata/releases-index.json",
+		client: &http.Client{
+			Timeout: 2 * time.Minute,
+			Transport: &http.Transport{
+				Proxy: http.ProxyFromEnvironment,
+				DialContext: (&net.Dialer{
+					Timeout:   30 * time.Second,
+					KeepAlive: 30 * time.Second,
+				}).DialContext,
+				TLSHandshakeTimeout:   10 * time.Second,
+				ResponseHeaderTimeout: 30 * time.Second,
+				MaxIdleConnsPerHost:   8,
+				IdleConnTimeout:       90 * time.Second,
+			},
+		},
+		concurrency: 4,
+		attempts:    5,
+		backoff:     time.Second,
 	}
 }
 
@@ -42,77 +128,417 @@ func (f Fetcher) WithReleaseIndex(uri string) Fetcher {
 	return f
 }
 
+// WithClient sets the HTTP client used for every request.
+func (f Fetcher) WithClient(client *http.Client) Fetcher {
+	f.client = client
+	return f
+}
+
+// WithConcurrency sets the maximum number of release pages fetched at once.
+func (f Fetcher) WithConcurrency(concurrency int) Fetcher {
+	f.concurrency = concurrency
+	return f
+}
+
+// WithRetries sets the maximum number of attempts made for each request and
+// the delay before the first retry, which doubles for each further retry.
+func (f Fetcher) WithRetries(attempts int, backoff time.Duration) Fetcher {
+	f.attempts = attempts
+	f.backoff = backoff
+	return f
+}
+
+// WithCache stores the fetched release metadata in dir and makes conditional
+// requests for it on later runs, reusing the stored copy when it has not
+// changed.
+func (f Fetcher) WithCache(dir string) Fetcher {
+	f.cacheDir = dir
+	return f
+}
+
+// WithOffline makes the fetcher read the release metadata from its cache
+// without making any requests. It requires a cache directory.
+func (f Fetcher) WithOffline(offline bool) Fetcher {
+	f.offline = offline
+	return f
+}
+
+// WithSupportPhases only fetches releases from channels in one of the given
+// support phases, e.g. "active" or "maintenance".
+func (f Fetcher) WithSupportPhases(phases ...string) Fetcher {
+	f.supportPhases = phases
+	return f
+}
+
+// WithChannels only fetches releases from the given channels, e.g. "8.0".
+func (f Fetcher) WithChannels(channels ...string) Fetcher {
+	f.channels = channels
+	return f
+}
+
+// WithLatestPerFeatureBand only keeps the n latest releases of each SDK
+// feature band, e.g. 8.0.1xx. All releases are kept when n is not positive.
+func (f Fetcher) WithLatestPerFeatureBand(n int) Fetcher {
+	f.latestPerFeatureBand = n
+	return f
+}
+
 func (f Fetcher) GetVersions() (versionology.VersionFetcherArray, error) {
-	response, err := http.Get(f.releaseIndex)
+	if f.offline && f.cacheDir == "" {
+		return nil, errors.New("offline mode requires a cache directory")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var releasesIndex struct {
+		ReleasesIndex []releaseChannel `json:"releases-index"`
+	}
+
+	err := f.fetchJSON(ctx, f.releaseIndex, &releasesIndex)
 	if err != nil {
 		return nil, err
 	}
-	defer response.Body.Close()
 
-	if !(response.StatusCode >= 200 && response.StatusCode < 300) {
-		return nil, fmt.Errorf("received a non 200 status code from %s: status code %d received", f.releaseIndex, response.StatusCode)
+	var channels []releaseChannel
+	for _, channel := range releasesIndex.ReleasesIndex {
+		if len(f.supportPhases) > 0 && !slices.Contains(f.supportPhases, channel.SupportPhase) {
+			continue
+		}
+
+		if len(f.channels) > 0 && !slices.Contains(f.channels, channel.ChannelVersion) {
+			continue
+		}
+
+		channels = append(channels, channel)
 	}
 
-	var releasesIndex struct {
-		ReleasesIndex []struct {
-			ReleaseJSON string `json:"releases.json"`
-		} `json:"releases-index"`
+	concurrency := f.concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	// Pages are fetched concurrently, but their releases are kept in the order
+	// of the index. The first failure cancels the requests still in flight.
+	pages := make([]versionology.VersionFetcherArray, len(channels))
+	errs := make([]error, len(channels))
+	semaphore := make(chan struct{}, concurrency)
+
+	var wg sync.WaitGroup
+	for i, channel := range channels {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			select {
+			case semaphore <- struct{}{}:
+				defer func() { <-semaphore }()
+			case <-ctx.Done():
+				return
+			}
+
+			pages[i], errs[i] = f.fetchReleasePage(ctx, channel)
+			if errs[i] != nil {
+				cancel()
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Report the failure that caused the cancellation rather than the
+	// cancellation errors of the requests it interrupted
+	for _, err := range errs {
+		if err != nil && !errors.Is(err, context.Canceled) {
+			return nil, err
+		}
+	}
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var releases versionology.VersionFetcherArray
+	for _, page := range pages {
+		releases = append(releases, page...)
+	}
+
+	if f.latestPerFeatureBand > 0 {
+		releases = latestPerFeatureBand(releases, f.latestPerFeatureBand)
+	}
+
+	return releases, nil
+}
+
+// releaseChannel is an entry of releases-index.json.
+type releaseChannel struct {
+	ChannelVersion string `json:"channel-version"`
+	SupportPhase   string `json:"support-phase"`
+	ReleaseType    string `json:"release-type"`
+	ReleaseJSON    string `json:"releases.json"`
+}
+
+// latestPerFeatureBand keeps the n highest releases of each feature band,
+// preserving the order of releases.
+func latestPerFeatureBand(releases versionology.VersionFetcherArray, n int) versionology.VersionFetcherArray {
+	bands := map[string][]*semver.Version{}
+	for _, release := range releases {
+		band := featureBand(release.Version())
+		bands[band] = append(bands[band], release.Version())
+	}
+
+	for band, versions := range bands {
+		slices.SortFunc(versions, func(a, b *semver.Version) int {
+			return b.Compare(a)
+		})
+		bands[band] = versions[:min(n, len(versions))]
 	}
 
-	err = json.NewDecoder(response.Body).Decode(&releasesIndex)
+	var latest versionology.VersionFetcherArray
+	for _, release := range releases {
+		if slices.ContainsFunc(bands[featureBand(release.Version())], release.Version().Equal) {
+			latest = append(latest, release)
+		}
+	}
+
+	return latest
+}
+
+// featureBand returns the SDK feature band of version, e.g. 8.0.1xx for
+// 8.0.105.
+func featureBand(version *semver.Version) string {
+	return fmt.Sprintf("%d.%d.%dxx", version.Major(), version.Minor(), version.Patch()/100)
+}
+
+func (f Fetcher) fetchReleasePage(ctx context.Context, channel releaseChannel) (versionology.VersionFetcherArray, error) {
+	var releasePage struct {
+		EOLDate  string `json:"eol-date"`
+		Releases []struct {
+			Sdk struct {
+				Version        string        `json:"version"`
+				RuntimeVersion string        `json:"runtime-version"`
+				VSVersion      string        `json:"vs-version"`
+				CSharpVersion  string        `json:"csharp-version"`
+				FSharpVersion  string        `json:"fsharp-version"`
+				Files          []ReleaseFile `json:"files"`
+			} `json:"sdk"`
+			Runtime struct {
+				Version string `json:"version"`
+			} `json:"runtime"`
+			AspNetCoreRuntime struct {
+				Version string `json:"version"`
+			} `json:"aspnetcore-runtime"`
+		} `json:"releases"`
+	}
+
+	err := f.fetchJSON(ctx, channel.ReleaseJSON, &releasePage)
 	if err != nil {
 		return nil, err
 	}
 
 	var releases versionology.VersionFetcherArray
-	for _, releaseIndex := range releasesIndex.ReleasesIndex {
-		releaseResponse, err := http.Get(releaseIndex.ReleaseJSON)
+	for _, r := range releasePage.Releases {
+		release := SdkRelease{
+			ReleaseVersion:    r.Sdk.Version,
+			Files:             r.Sdk.Files,
+			RuntimeVersion:    r.Sdk.RuntimeVersion,
+			AspNetCoreVersion: r.AspNetCoreRuntime.Version,
+			VSVersion:         r.Sdk.VSVersion,
+			CSharpVersion:     r.Sdk.CSharpVersion,
+			FSharpVersion:     r.Sdk.FSharpVersion,
+			ChannelVersion:    channel.ChannelVersion,
+			SupportPhase:      channel.SupportPhase,
+			ReleaseType:       channel.ReleaseType,
+		}
+
+		if release.RuntimeVersion == "" {
+			release.RuntimeVersion = r.Runtime.Version
+		}
+
+		// There are some 2.1 releases that have no data attached these are
+		// eliminated with this check.
+		if r.Sdk.Version == "" {
+			continue
+		}
+
+		release.EOLDate = releasePage.EOLDate
+		release.SemVer, err = semver.NewVersion(r.Sdk.Version)
 		if err != nil {
 			return nil, err
 		}
-		defer releaseResponse.Body.Close()
 
-		if !(releaseResponse.StatusCode >= 200 && releaseResponse.StatusCode < 300) {
-			return nil, fmt.Errorf("received a non 200 status code from %s: status code %d received", releaseIndex.ReleaseJSON, releaseResponse.StatusCode)
+		releases = append(releases, release)
+	}
+
+	return releases, nil
+}
+
+// fetchJSON decodes the JSON document at uri into v. Requests that fail with a
+// network error, a 5xx or a 429 status code are retried with exponential
+// backoff until the attempts are exhausted or ctx is cancelled.
+func (f Fetcher) fetchJSON(ctx context.Context, uri string, v interface{}) error {
+	attempts := f.attempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	delay := f.backoff
+	for attempt := 1; ; attempt++ {
+		retryAfter, err := f.tryFetchJSON(ctx, uri, v)
+		if err == nil {
+			return nil
+		}
+
+		var transient transientError
+		if !errors.As(err, &transient) || attempt == attempts || ctx.Err() != nil {
+			return err
+		}
+
+		wait := delay
+		if retryAfter > wait {
+			wait = retryAfter
 		}
 
-		var releasePage struct {
-			EOLDate  string `json:"eol-date"`
-			Releases []struct {
-				Sdk struct {
-					Version string        `json:"version"`
-					Files   []ReleaseFile `json:"files"`
-				} `json:"sdk"`
-			} `json:"releases"`
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
 		}
 
-		err = json.NewDecoder(releaseResponse.Body).Decode(&releasePage)
+		delay *= 2
+	}
+}
+
+// tryFetchJSON makes a single request for uri. Failures that may succeed when
+// retried are returned as a transientError, along with the delay requested by
+// a Retry-After header, if any. With a cache directory, the request is
+// conditional on the cached copy having changed.
+func (f Fetcher) tryFetchJSON(ctx context.Context, uri string, v interface{}) (time.Duration, error) {
+	var cache *responseCache
+	var cached *cachedResponse
+	if f.cacheDir != "" {
+		cache = &responseCache{dir: f.cacheDir}
+
+		var err error
+		cached, err = cache.Load(uri)
 		if err != nil {
-			return nil, err
+			return 0, err
 		}
+	}
 
-		for _, r := range releasePage.Releases {
-			release := SdkRelease{
-				ReleaseVersion: r.Sdk.Version,
-				Files:          r.Sdk.Files,
-			}
+	if f.offline {
+		if cached == nil {
+			return 0, fmt.Errorf("no cached copy of %s is available in offline mode", uri)
+		}
+		return 0, decodeJSON([]byte(cached.Body), v)
+	}
 
-			// There are some 2.1 releases that have no data attached these are
-			// eliminated with this check.
-			if r.Sdk.Version == "" {
-				continue
-			}
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return 0, err
+	}
 
-			release.EOLDate = releasePage.EOLDate
-			release.SemVer, err = semver.NewVersion(r.Sdk.Version)
-			if err != nil {
-				return nil, err
-			}
+	if cached != nil {
+		if cached.ETag != "" {
+			request.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			request.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+
+	client := f.client
+	if client == nil {
+		client = http.DefaultClient
+	}
 
-			releases = append(releases, release)
+	response, err := client.Do(request)
+	if err != nil {
+		if isTransient(err) {
+			return 0, transientError{err}
 		}
+		return 0, err
+	}
+	defer response.Body.Close()
 
+	if response.StatusCode == http.StatusNotModified && cached != nil {
+		_, _ = io.Copy(io.Discard, response.Body)
+		return 0, decodeJSON([]byte(cached.Body), v)
 	}
 
-	return releases, nil
+	if !(response.StatusCode >= 200 && response.StatusCode < 300) {
+		// Drain the body so that the connection can be reused
+		_, _ = io.Copy(io.Discard, response.Body)
+
+		err = fmt.Errorf("received a non 200 status code from %s: status code %d received", uri, response.StatusCode)
+		if response.StatusCode >= 500 || response.StatusCode == http.StatusTooManyRequests {
+			seconds, _ := strconv.Atoi(response.Header.Get("Retry-After"))
+			return time.Duration(seconds) * time.Second, transientError{err}
+		}
+		return 0, err
+	}
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		if isTransient(err) {
+			return 0, transientError{err}
+		}
+		return 0, err
+	}
+
+	err = decodeJSON(body, v)
+	if err != nil {
+		return 0, err
+	}
+
+	if cache != nil {
+		err = cache.Store(cachedResponse{
+			URL:          uri,
+			ETag:         response.Header.Get("ETag"),
+			LastModified: response.Header.Get("Last-Modified"),
+			Body:         string(body),
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return 0, nil
+}
+
+// decodeJSON decodes the first JSON value in body, ignoring anything after it
+// as the decoding of a response stream would.
+func decodeJSON(body []byte, v interface{}) error {
+	return json.NewDecoder(bytes.NewReader(body)).Decode(v)
+}
+
+type transientError struct {
+	err error
+}
+
+func (e transientError) Error() string {
+	return e.err.Error()
+}
+
+func (e transientError) Unwrap() error {
+	return e.err
+}
+
+// isTransient reports whether err is a network failure, such as a timeout or
+// a dropped connection, rather than a problem with the request itself.
+func isTransient(err error) bool {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		err = urlErr.Err
+	}
+
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) ||
+		errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, io.EOF)
 }
@@ -4,7 +4,12 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/Masterminds/semver/v3"
 	"github.com/paketo-buildpacks/dotnet-core-sdk/dependency/retrieval/components"
@@ -17,9 +22,18 @@ import (
 func testReleases(t *testing.T, context spec.G, it spec.S) {
 
 	var (
-		Expect = NewWithT(t).Expect
+		Expect     = NewWithT(t).Expect
+		Eventually = NewWithT(t).Eventually
 	)
 
+	versionStrings := func(releases []versionology.VersionFetcher) []string {
+		var versions []string
+		for _, release := range releases {
+			versions = append(versions, release.Version().String())
+		}
+		return versions
+	}
+
 	context("GetReleases", func() {
 		var (
 			fetcher components.Fetcher
@@ -41,8 +55,18 @@ func testReleases(t *testing.T, context spec.G, it spec.S) {
 					fmt.Fprintln(w, `{
 	"eol-date": "2024-11-12",
 	"releases": [{
+		"runtime": {
+			"version": "6.0.9"
+		},
+		"aspnetcore-runtime": {
+			"version": "6.0.9"
+		},
 		"sdk": {
 			"version": "6.0.401",
+			"runtime-version": "6.0.9",
+			"vs-version": "17.3.4",
+			"csharp-version": "10.0",
+			"fsharp-version": "6.0",
 			"files": [{
 				"name": "dotnet-sdk-linux-arm.tar.gz",
 				"rid": "linux-arm",
@@ -52,6 +76,12 @@ func testReleases(t *testing.T, context spec.G, it spec.S) {
 		}
 	},
   {
+		"runtime": {
+			"version": "6.0.8"
+		},
+		"aspnetcore-runtime": {
+			"version": "6.0.8"
+		},
 		"sdk": {
 			"version": "6.0.400",
 			"files": [{
@@ -124,9 +154,15 @@ func testReleases(t *testing.T, context spec.G, it spec.S) {
 					fmt.Fprintf(w, `{
     "releases-index": [
         {
+            "channel-version": "6.0",
+            "support-phase": "active",
+            "release-type": "lts",
             "releases.json": "%[1]s/6.0"
         },
 				{
+            "channel-version": "3.1",
+            "support-phase": "eol",
+            "release-type": "lts",
             "releases.json": "%[1]s/3.1"
 				}
     ]
@@ -193,9 +229,17 @@ func testReleases(t *testing.T, context spec.G, it spec.S) {
 
 			Expect(releases).To(BeEquivalentTo([]versionology.VersionFetcher{
 				components.SdkRelease{
-					SemVer:         semver.MustParse("6.0.401"),
-					EOLDate:        "2024-11-12",
-					ReleaseVersion: "6.0.401",
+					SemVer:            semver.MustParse("6.0.401"),
+					EOLDate:           "2024-11-12",
+					ReleaseVersion:    "6.0.401",
+					ChannelVersion:    "6.0",
+					SupportPhase:      "active",
+					ReleaseType:       "lts",
+					RuntimeVersion:    "6.0.9",
+					AspNetCoreVersion: "6.0.9",
+					VSVersion:         "17.3.4",
+					CSharpVersion:     "10.0",
+					FSharpVersion:     "6.0",
 					Files: []components.ReleaseFile{
 						{
 							Name: "dotnet-sdk-linux-arm.tar.gz",
@@ -206,9 +250,14 @@ func testReleases(t *testing.T, context spec.G, it spec.S) {
 					},
 				},
 				components.SdkRelease{
-					SemVer:         semver.MustParse("6.0.400"),
-					EOLDate:        "2024-11-12",
-					ReleaseVersion: "6.0.400",
+					SemVer:            semver.MustParse("6.0.400"),
+					EOLDate:           "2024-11-12",
+					ReleaseVersion:    "6.0.400",
+					ChannelVersion:    "6.0",
+					SupportPhase:      "active",
+					ReleaseType:       "lts",
+					RuntimeVersion:    "6.0.8",
+					AspNetCoreVersion: "6.0.8",
 					Files: []components.ReleaseFile{
 						{
 							Name: "dotnet-sdk-linux-arm.tar.gz",
@@ -222,6 +271,9 @@ func testReleases(t *testing.T, context spec.G, it spec.S) {
 					SemVer:         semver.MustParse("3.1.423"),
 					EOLDate:        "2022-12-13",
 					ReleaseVersion: "3.1.423",
+					ChannelVersion: "3.1",
+					SupportPhase:   "eol",
+					ReleaseType:    "lts",
 					Files: []components.ReleaseFile{
 						{
 							Name: "dotnet-sdk-linux-arm.tar.gz",
@@ -234,6 +286,42 @@ func testReleases(t *testing.T, context spec.G, it spec.S) {
 			}))
 		})
 
+		context("when filtering by support phase", func() {
+			it.Before(func() {
+				fetcher = fetcher.WithSupportPhases("active", "maintenance")
+			})
+
+			it("only returns releases from channels in those phases", func() {
+				releases, err := fetcher.GetVersions()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(versionStrings(releases)).To(Equal([]string{"6.0.401", "6.0.400"}))
+			})
+		})
+
+		context("when filtering by channel", func() {
+			it.Before(func() {
+				fetcher = fetcher.WithChannels("3.1")
+			})
+
+			it("only returns releases from those channels", func() {
+				releases, err := fetcher.GetVersions()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(versionStrings(releases)).To(Equal([]string{"3.1.423"}))
+			})
+		})
+
+		context("when limiting the releases per feature band", func() {
+			it.Before(func() {
+				fetcher = fetcher.WithLatestPerFeatureBand(1)
+			})
+
+			it("only returns the latest releases of each feature band", func() {
+				releases, err := fetcher.GetVersions()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(versionStrings(releases)).To(Equal([]string{"6.0.401", "3.1.423"}))
+			})
+		})
+
 		context("failure cases", func() {
 			context("when the index page get fails", func() {
 				it.Before(func() {
@@ -313,4 +401,373 @@ func testReleases(t *testing.T, context spec.G, it spec.S) {
 			})
 		})
 	})
+
+	context("when the release pages are served unreliably", func() {
+		var (
+			fetcher components.Fetcher
+			server  *httptest.Server
+
+			mutex    sync.Mutex
+			requests map[string]int
+			handlers map[string]func(w http.ResponseWriter, req *http.Request, attempt int)
+
+			inFlight    atomic.Int32
+			maxInFlight atomic.Int32
+		)
+
+		page := func(version string) string {
+			return fmt.Sprintf(`{"eol-date": "2030-01-01", "releases": [{"sdk": {"version": %q}}]}`, version)
+		}
+
+		it.Before(func() {
+			requests = map[string]int{}
+			handlers = map[string]func(w http.ResponseWriter, req *http.Request, attempt int){}
+			inFlight.Store(0)
+			maxInFlight.Store(0)
+
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				current := inFlight.Add(1)
+				defer inFlight.Add(-1)
+				for {
+					observed := maxInFlight.Load()
+					if current <= observed || maxInFlight.CompareAndSwap(observed, current) {
+						break
+					}
+				}
+
+				mutex.Lock()
+				requests[req.URL.Path]++
+				attempt := requests[req.URL.Path]
+				handler, ok := handlers[req.URL.Path]
+				mutex.Unlock()
+
+				if !ok {
+					t.Errorf("unknown path: %s", req.URL.Path)
+					return
+				}
+
+				handler(w, req, attempt)
+			}))
+
+			handlers["/index"] = func(w http.ResponseWriter, req *http.Request, attempt int) {
+				fmt.Fprintf(w, `{"releases-index": [{"releases.json": "%[1]s/8.0"}, {"releases.json": "%[1]s/6.0"}]}`, server.URL)
+			}
+			handlers["/8.0"] = func(w http.ResponseWriter, req *http.Request, attempt int) {
+				fmt.Fprint(w, page("8.0.100"))
+			}
+			handlers["/6.0"] = func(w http.ResponseWriter, req *http.Request, attempt int) {
+				fmt.Fprint(w, page("6.0.100"))
+			}
+
+			fetcher = components.NewFetcher().
+				WithReleaseIndex(fmt.Sprintf("%s/index", server.URL)).
+				WithRetries(3, time.Millisecond)
+		})
+
+		it.After(func() {
+			server.Close()
+		})
+
+		requestCount := func(path string) int {
+			mutex.Lock()
+			defer mutex.Unlock()
+			return requests[path]
+		}
+
+		context("when a page fails with a 5xx status code", func() {
+			it.Before(func() {
+				handlers["/8.0"] = func(w http.ResponseWriter, req *http.Request, attempt int) {
+					if attempt < 3 {
+						w.WriteHeader(http.StatusServiceUnavailable)
+						return
+					}
+					fmt.Fprint(w, page("8.0.100"))
+				}
+			})
+
+			it("retries the request", func() {
+				releases, err := fetcher.GetVersions()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(versionStrings(releases)).To(Equal([]string{"8.0.100", "6.0.100"}))
+				Expect(requestCount("/8.0")).To(Equal(3))
+			})
+		})
+
+		context("when the index is rate limited", func() {
+			it.Before(func() {
+				index := handlers["/index"]
+				handlers["/index"] = func(w http.ResponseWriter, req *http.Request, attempt int) {
+					if attempt == 1 {
+						w.WriteHeader(http.StatusTooManyRequests)
+						return
+					}
+					index(w, req, attempt)
+				}
+			})
+
+			it("retries the request", func() {
+				releases, err := fetcher.GetVersions()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(versionStrings(releases)).To(Equal([]string{"8.0.100", "6.0.100"}))
+				Expect(requestCount("/index")).To(Equal(2))
+			})
+		})
+
+		context("when the connection is dropped", func() {
+			it.Before(func() {
+				handlers["/6.0"] = func(w http.ResponseWriter, req *http.Request, attempt int) {
+					if attempt == 1 {
+						conn, _, err := w.(http.Hijacker).Hijack()
+						Expect(err).NotTo(HaveOccurred())
+						Expect(conn.Close()).To(Succeed())
+						return
+					}
+					fmt.Fprint(w, page("6.0.100"))
+				}
+			})
+
+			it("retries the request", func() {
+				releases, err := fetcher.GetVersions()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(versionStrings(releases)).To(Equal([]string{"8.0.100", "6.0.100"}))
+				Expect(requestCount("/6.0")).To(Equal(2))
+			})
+		})
+
+		context("when there are more pages than the concurrency limit", func() {
+			it.Before(func() {
+				var index string
+				for i := 0; i < 8; i++ {
+					path := fmt.Sprintf("/%d.0", i+1)
+					handlers[path] = func(w http.ResponseWriter, req *http.Request, attempt int) {
+						time.Sleep(10 * time.Millisecond)
+						fmt.Fprint(w, page(fmt.Sprintf("%d.0.100", i+1)))
+					}
+					if index != "" {
+						index += ","
+					}
+					index += fmt.Sprintf(`{"releases.json": "%s%s"}`, server.URL, path)
+				}
+
+				handlers["/index"] = func(w http.ResponseWriter, req *http.Request, attempt int) {
+					fmt.Fprintf(w, `{"releases-index": [%s]}`, index)
+				}
+
+				fetcher = fetcher.WithConcurrency(2)
+			})
+
+			it("fetches no more pages at once than the limit, keeping the index order", func() {
+				releases, err := fetcher.GetVersions()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(versionStrings(releases)).To(Equal([]string{"1.0.100", "2.0.100", "3.0.100", "4.0.100", "5.0.100", "6.0.100", "7.0.100", "8.0.100"}))
+				Expect(maxInFlight.Load()).To(BeNumerically("<=", 2))
+			})
+		})
+
+		context("failure cases", func() {
+			context("when a page keeps failing with a 5xx status code", func() {
+				it.Before(func() {
+					handlers["/8.0"] = func(w http.ResponseWriter, req *http.Request, attempt int) {
+						w.WriteHeader(http.StatusInternalServerError)
+					}
+				})
+
+				it("returns an error once the attempts are exhausted", func() {
+					_, err := fetcher.GetVersions()
+					Expect(err).To(MatchError(fmt.Sprintf("received a non 200 status code from %s/8.0: status code 500 received", server.URL)))
+					Expect(requestCount("/8.0")).To(Equal(3))
+				})
+			})
+
+			context("when a page fails with a client error", func() {
+				it.Before(func() {
+					handlers["/8.0"] = func(w http.ResponseWriter, req *http.Request, attempt int) {
+						w.WriteHeader(http.StatusNotFound)
+					}
+				})
+
+				it("does not retry the request", func() {
+					_, err := fetcher.GetVersions()
+					Expect(err).To(MatchError(fmt.Sprintf("received a non 200 status code from %s/8.0: status code 404 received", server.URL)))
+					Expect(requestCount("/8.0")).To(Equal(1))
+				})
+			})
+
+			context("when a page fails while another is still being fetched", func() {
+				var cancelled chan bool
+
+				it.Before(func() {
+					cancelled = make(chan bool, 1)
+
+					handlers["/8.0"] = func(w http.ResponseWriter, req *http.Request, attempt int) {
+						select {
+						case <-req.Context().Done():
+							cancelled <- true
+						case <-time.After(5 * time.Second):
+							cancelled <- false
+						}
+					}
+					handlers["/6.0"] = func(w http.ResponseWriter, req *http.Request, attempt int) {
+						time.Sleep(10 * time.Millisecond)
+						w.WriteHeader(http.StatusNotFound)
+					}
+				})
+
+				it("cancels the other request and returns the failure", func() {
+					_, err := fetcher.GetVersions()
+					Expect(err).To(MatchError(fmt.Sprintf("received a non 200 status code from %s/6.0: status code 404 received", server.URL)))
+					Eventually(cancelled).Should(Receive(BeTrue()))
+				})
+			})
+		})
+	})
+
+	context("when a cache directory is set", func() {
+		var (
+			fetcher  components.Fetcher
+			server   *httptest.Server
+			cacheDir string
+
+			mutex       sync.Mutex
+			served      map[string]int
+			notModified map[string]int
+			versions    map[string]string
+		)
+
+		it.Before(func() {
+			served = map[string]int{}
+			notModified = map[string]int{}
+			versions = map[string]string{
+				"/8.0": "8.0.100",
+				"/6.0": "6.0.100",
+			}
+
+			// The index is validated with an ETag and the release pages with
+			// their modification time
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				mutex.Lock()
+				defer mutex.Unlock()
+
+				switch req.URL.Path {
+				case "/index":
+					if req.Header.Get("If-None-Match") == `"index-etag"` {
+						notModified[req.URL.Path]++
+						w.WriteHeader(http.StatusNotModified)
+						return
+					}
+
+					served[req.URL.Path]++
+					w.Header().Set("ETag", `"index-etag"`)
+					fmt.Fprintf(w, `{"releases-index": [{"releases.json": "%[1]s/8.0"}, {"releases.json": "%[1]s/6.0"}]}`, server.URL)
+
+				case "/8.0", "/6.0":
+					lastModified := "Mon, 01 Jan 2024 00:00:00 GMT"
+					if versions[req.URL.Path] == "6.0.101" {
+						lastModified = "Tue, 02 Jan 2024 00:00:00 GMT"
+					}
+
+					if req.Header.Get("If-Modified-Since") == lastModified {
+						notModified[req.URL.Path]++
+						w.WriteHeader(http.StatusNotModified)
+						return
+					}
+
+					served[req.URL.Path]++
+					w.Header().Set("Last-Modified", lastModified)
+					fmt.Fprintf(w, `{"eol-date": "2030-01-01", "releases": [{"sdk": {"version": %q}}]}`, versions[req.URL.Path])
+
+				default:
+					t.Errorf("unknown path: %s", req.URL.Path)
+				}
+			}))
+
+			cacheDir = t.TempDir()
+			fetcher = components.NewFetcher().
+				WithReleaseIndex(fmt.Sprintf("%s/index", server.URL)).
+				WithCache(cacheDir)
+		})
+
+		it.After(func() {
+			server.Close()
+		})
+
+		it("reuses the cached copies that have not changed", func() {
+			releases, err := fetcher.GetVersions()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versionStrings(releases)).To(Equal([]string{"8.0.100", "6.0.100"}))
+			Expect(served).To(Equal(map[string]int{"/index": 1, "/8.0": 1, "/6.0": 1}))
+
+			entries, err := os.ReadDir(cacheDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entries).To(HaveLen(3))
+
+			mutex.Lock()
+			versions["/6.0"] = "6.0.101"
+			mutex.Unlock()
+
+			releases, err = fetcher.GetVersions()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versionStrings(releases)).To(Equal([]string{"8.0.100", "6.0.101"}))
+			Expect(served).To(Equal(map[string]int{"/index": 1, "/8.0": 1, "/6.0": 2}))
+			Expect(notModified).To(Equal(map[string]int{"/index": 1, "/8.0": 1}))
+
+			releases, err = fetcher.GetVersions()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versionStrings(releases)).To(Equal([]string{"8.0.100", "6.0.101"}))
+			Expect(served).To(Equal(map[string]int{"/index": 1, "/8.0": 1, "/6.0": 2}))
+			Expect(notModified).To(Equal(map[string]int{"/index": 2, "/8.0": 2, "/6.0": 1}))
+		})
+
+		context("when offline", func() {
+			it("reads the cached copies without making any requests", func() {
+				_, err := fetcher.GetVersions()
+				Expect(err).NotTo(HaveOccurred())
+
+				server.Close()
+
+				releases, err := fetcher.WithOffline(true).GetVersions()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(versionStrings(releases)).To(Equal([]string{"8.0.100", "6.0.100"}))
+				Expect(served).To(Equal(map[string]int{"/index": 1, "/8.0": 1, "/6.0": 1}))
+				Expect(notModified).To(BeEmpty())
+			})
+
+			context("failure cases", func() {
+				context("when there is no cached copy", func() {
+					it("returns an error", func() {
+						_, err := fetcher.WithOffline(true).GetVersions()
+						Expect(err).To(MatchError(fmt.Sprintf("no cached copy of %s/index is available in offline mode", server.URL)))
+						Expect(served).To(BeEmpty())
+					})
+				})
+
+				context("when there is no cache directory", func() {
+					it("returns an error", func() {
+						_, err := fetcher.WithCache("").WithOffline(true).GetVersions()
+						Expect(err).To(MatchError("offline mode requires a cache directory"))
+					})
+				})
+			})
+		})
+
+		context("failure cases", func() {
+			context("when a cached copy cannot be parsed", func() {
+				it.Before(func() {
+					_, err := fetcher.GetVersions()
+					Expect(err).NotTo(HaveOccurred())
+
+					entries, err := os.ReadDir(cacheDir)
+					Expect(err).NotTo(HaveOccurred())
+					for _, entry := range entries {
+						Expect(os.WriteFile(filepath.Join(cacheDir, entry.Name()), []byte(`%%%`), 0600)).To(Succeed())
+					}
+				})
+
+				it("returns an error", func() {
+					_, err := fetcher.GetVersions()
+					Expect(err).To(MatchError(ContainSubstring(fmt.Sprintf("failed to parse cached response for %s/index", server.URL))))
+				})
+			})
+		})
+	})
 }
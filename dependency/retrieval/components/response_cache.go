@@ -0,0 +1,89 @@
+package components
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// cachedResponse is a response body stored in the cache directory along with
+// the validators used to make conditional requests for it.
+type cachedResponse struct {
+	URL          string `json:"url"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last-modified,omitempty"`
+	Body         string `json:"body"`
+}
+
+// responseCache stores one file per URL in dir.
+type responseCache struct {
+	dir string
+}
+
+func (c responseCache) path(uri string) string {
+	sum := sha256.Sum256([]byte(uri))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// Load returns the cached response for uri, or nil when there is none.
+func (c responseCache) Load(uri string) (*cachedResponse, error) {
+	content, err := os.ReadFile(c.path(uri))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read cached response for %s: %w", uri, err)
+	}
+
+	var response cachedResponse
+	err = json.Unmarshal(content, &response)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse cached response for %s: %w", uri, err)
+	}
+
+	// Guard against the unlikely case of a hash collision
+	if response.URL != uri {
+		return nil, nil
+	}
+
+	return &response, nil
+}
+
+// Store writes the response to the cache, replacing any previous copy
+// atomically so that an interrupted run never leaves a truncated entry.
+func (c responseCache) Store(response cachedResponse) error {
+	content, err := json.Marshal(response)
+	if err != nil {
+		return fmt.Errorf("failed to serialize cached response for %s: %w", response.URL, err)
+	}
+
+	err = os.MkdirAll(c.dir, os.ModePerm)
+	if err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	file, err := os.CreateTemp(c.dir, "response-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to cache response for %s: %w", response.URL, err)
+	}
+	defer os.Remove(file.Name())
+
+	_, err = file.Write(content)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to cache response for %s: %w", response.URL, err)
+	}
+
+	err = os.Rename(file.Name(), c.path(response.URL))
+	if err != nil {
+		return fmt.Errorf("failed to cache response for %s: %w", response.URL, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,129 @@
+package components
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/paketo-buildpacks/packit/v2/cargo"
+)
+
+// SdkDetailsKey is the buildpack.toml metadata table that holds the release
+// details of each SDK version.
+const SdkDetailsKey = "sdk-details"
+
+// sdkDetailKeys are the fields added by AddReleaseMetadata.
+var sdkDetailKeys = []string{"runtime-version", "aspnetcore-version", "vs-version", "csharp-version", "fsharp-version"}
+
+// UpdateSdkDetails copies the release details that AddReleaseMetadata added to
+// the dependencies in the metadata file at metadataPath into the
+// [[metadata.sdk-details]] table of the buildpack.toml at buildpackTOMLPath,
+// with one entry per SDK version. The workflow step that adds the new
+// dependencies to buildpack.toml decodes them into
+// cargo.ConfigMetadataDependency, which drops these fields, but keeps the
+// metadata tables it does not know. Entries for SDK versions that are no
+// longer in buildpack.toml are removed.
+func UpdateSdkDetails(buildpackTOMLPath, metadataPath string) error {
+	content, err := os.ReadFile(metadataPath)
+	if err != nil {
+		return fmt.Errorf("failed to read metadata file: %w", err)
+	}
+
+	var dependencies []map[string]interface{}
+	err = json.Unmarshal(content, &dependencies)
+	if err != nil {
+		return fmt.Errorf("failed to parse metadata file: %w", err)
+	}
+
+	file, err := os.Open(buildpackTOMLPath)
+	if err != nil {
+		return fmt.Errorf("failed to read buildpack.toml: %w", err)
+	}
+	defer file.Close()
+
+	var config cargo.Config
+	err = cargo.DecodeConfig(file, &config)
+	if err != nil {
+		return fmt.Errorf("failed to parse buildpack.toml: %w", err)
+	}
+
+	details := map[string]map[string]interface{}{}
+	if existing, ok := config.Metadata.Unstructured[SdkDetailsKey]; ok {
+		content, err := json.Marshal(existing)
+		if err != nil {
+			return fmt.Errorf("failed to read %s from buildpack.toml: %w", SdkDetailsKey, err)
+		}
+
+		var entries []map[string]interface{}
+		err = json.Unmarshal(content, &entries)
+		if err != nil {
+			return fmt.Errorf("failed to read %s from buildpack.toml: %w", SdkDetailsKey, err)
+		}
+
+		for _, entry := range entries {
+			version, _ := entry["version"].(string)
+			details[version] = entry
+		}
+	}
+
+	for _, dependency := range dependencies {
+		version, _ := dependency["version"].(string)
+
+		entry := map[string]interface{}{"version": version}
+		for _, key := range sdkDetailKeys {
+			if value, ok := dependency[key]; ok {
+				entry[key] = value
+			}
+		}
+
+		if len(entry) > 1 {
+			details[version] = entry
+		}
+	}
+
+	var versions []string
+	for _, dependency := range config.Metadata.Dependencies {
+		if _, ok := details[dependency.Version]; ok && !slices.Contains(versions, dependency.Version) {
+			versions = append(versions, dependency.Version)
+		}
+	}
+
+	slices.SortFunc(versions, func(a, b string) int {
+		aVersion, aErr := semver.NewVersion(a)
+		bVersion, bErr := semver.NewVersion(b)
+		if aErr != nil || bErr != nil {
+			return strings.Compare(a, b)
+		}
+
+		return aVersion.Compare(bVersion)
+	})
+
+	var entries []map[string]interface{}
+	for _, version := range versions {
+		entries = append(entries, details[version])
+	}
+
+	if config.Metadata.Unstructured == nil {
+		config.Metadata.Unstructured = map[string]interface{}{}
+	}
+	delete(config.Metadata.Unstructured, SdkDetailsKey)
+	if len(entries) > 0 {
+		config.Metadata.Unstructured[SdkDetailsKey] = entries
+	}
+
+	output, err := os.Create(buildpackTOMLPath)
+	if err != nil {
+		return fmt.Errorf("failed to write buildpack.toml: %w", err)
+	}
+	defer output.Close()
+
+	err = cargo.EncodeConfig(output, config)
+	if err != nil {
+		return fmt.Errorf("failed to write buildpack.toml: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,168 @@
+package components_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/Masterminds/semver/v3"
+	"github.com/paketo-buildpacks/dotnet-core-sdk/dependency/retrieval/components"
+	"github.com/paketo-buildpacks/libdependency/versionology"
+	"github.com/paketo-buildpacks/packit/v2/cargo"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testSdkDetails(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		buildpackTOMLPath string
+		metadataPath      string
+	)
+
+	// updateFromMetadata adds the dependencies in the metadata file to
+	// buildpack.toml the way the update-from-metadata workflow step does
+	updateFromMetadata := func(metadataPath string) {
+		content, err := os.ReadFile(metadataPath)
+		Expect(err).NotTo(HaveOccurred())
+
+		var dependencies []cargo.ConfigMetadataDependency
+		Expect(json.Unmarshal(content, &dependencies)).To(Succeed())
+
+		file, err := os.Open(buildpackTOMLPath)
+		Expect(err).NotTo(HaveOccurred())
+
+		var config cargo.Config
+		Expect(cargo.DecodeConfig(file, &config)).To(Succeed())
+		Expect(file.Close()).To(Succeed())
+
+		config.Metadata.Dependencies = append(config.Metadata.Dependencies, dependencies...)
+
+		file, err = os.Create(buildpackTOMLPath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cargo.EncodeConfig(file, config)).To(Succeed())
+		Expect(file.Close()).To(Succeed())
+	}
+
+	type sdkDetails struct {
+		Version           string `toml:"version"`
+		RuntimeVersion    string `toml:"runtime-version"`
+		AspNetCoreVersion string `toml:"aspnetcore-version"`
+		VSVersion         string `toml:"vs-version"`
+		CSharpVersion     string `toml:"csharp-version"`
+		FSharpVersion     string `toml:"fsharp-version"`
+	}
+
+	// readBuildpackTOML decodes the fields that the buildpack reads at build
+	// time
+	readBuildpackTOML := func() (dependencies []map[string]interface{}, details []sdkDetails) {
+		var buildpackTOML struct {
+			Metadata struct {
+				Dependencies []map[string]interface{} `toml:"dependencies"`
+				SdkDetails   []sdkDetails             `toml:"sdk-details"`
+			} `toml:"metadata"`
+		}
+		_, err := toml.DecodeFile(buildpackTOMLPath, &buildpackTOML)
+		Expect(err).NotTo(HaveOccurred())
+
+		return buildpackTOML.Metadata.Dependencies, buildpackTOML.Metadata.SdkDetails
+	}
+
+	it.Before(func() {
+		dir := t.TempDir()
+		buildpackTOMLPath = filepath.Join(dir, "buildpack.toml")
+		metadataPath = filepath.Join(dir, "metadata.json")
+
+		Expect(os.WriteFile(buildpackTOMLPath, []byte(`api = "0.8"
+
+[buildpack]
+  id = "paketo-buildpacks/dotnet-core-sdk"
+
+[metadata]
+  [[metadata.dependencies]]
+    id = "dotnet-sdk"
+    version = "8.0.100"
+    stacks = ["*"]
+    checksum = "sha512:some-checksum"
+
+  [[metadata.sdk-details]]
+    version = "8.0.100"
+    runtime-version = "8.0.0"
+
+  [[metadata.sdk-details]]
+    version = "7.0.100"
+    runtime-version = "7.0.0"
+`), 0600)).To(Succeed())
+
+		Expect(os.WriteFile(metadataPath, []byte(`[{"id":"dotnet-sdk","version":"8.0.401","stacks":["*"],"arch":"amd64","checksum":"sha512:amd64-checksum"},{"id":"dotnet-sdk","version":"8.0.401","stacks":["*"],"arch":"arm64","checksum":"sha512:arm64-checksum"}]`), 0600)).To(Succeed())
+		Expect(components.AddReleaseMetadata(metadataPath, versionology.VersionFetcherArray{
+			components.SdkRelease{
+				SemVer:            semver.MustParse("8.0.401"),
+				ReleaseVersion:    "8.0.401",
+				RuntimeVersion:    "8.0.8",
+				AspNetCoreVersion: "8.0.8",
+				VSVersion:         "17.11.0",
+				CSharpVersion:     "12.0",
+				FSharpVersion:     "8.0",
+			},
+		})).To(Succeed())
+	})
+
+	context("UpdateSdkDetails", func() {
+		it("keeps the release details in buildpack.toml through the update-from-metadata step", func() {
+			updateFromMetadata(metadataPath)
+
+			dependencies, _ := readBuildpackTOML()
+			Expect(dependencies).To(HaveLen(3))
+			Expect(dependencies[1]).NotTo(HaveKey("runtime-version"))
+
+			Expect(components.UpdateSdkDetails(buildpackTOMLPath, metadataPath)).To(Succeed())
+
+			// A later run of the workflow adds a release without any details
+			Expect(os.WriteFile(metadataPath, []byte(`[{"id":"dotnet-sdk","version":"9.0.100","stacks":["*"],"checksum":"sha512:other-checksum"}]`), 0600)).To(Succeed())
+			updateFromMetadata(metadataPath)
+			Expect(components.UpdateSdkDetails(buildpackTOMLPath, metadataPath)).To(Succeed())
+
+			dependencies, details := readBuildpackTOML()
+			Expect(dependencies).To(HaveLen(4))
+			Expect(details).To(Equal([]sdkDetails{
+				{
+					Version:        "8.0.100",
+					RuntimeVersion: "8.0.0",
+				},
+				{
+					Version:           "8.0.401",
+					RuntimeVersion:    "8.0.8",
+					AspNetCoreVersion: "8.0.8",
+					VSVersion:         "17.11.0",
+					CSharpVersion:     "12.0",
+					FSharpVersion:     "8.0",
+				},
+			}))
+		})
+
+		context("failure cases", func() {
+			context("when the metadata file does not exist", func() {
+				it("returns an error", func() {
+					err := components.UpdateSdkDetails(buildpackTOMLPath, filepath.Join(t.TempDir(), "missing.json"))
+					Expect(err).To(MatchError(ContainSubstring("failed to read metadata file")))
+				})
+			})
+
+			context("when buildpack.toml cannot be parsed", func() {
+				it.Before(func() {
+					Expect(os.WriteFile(buildpackTOMLPath, []byte(`%%%`), 0600)).To(Succeed())
+				})
+
+				it("returns an error", func() {
+					err := components.UpdateSdkDetails(buildpackTOMLPath, metadataPath)
+					Expect(err).To(MatchError(ContainSubstring("failed to parse buildpack.toml")))
+				})
+			})
+		})
+	})
+}
@@ -0,0 +1,131 @@
+package components
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// ValidationStrategy determines how GenerateMetadata checks the hash of each
+// SDK archive before recording it.
+type ValidationStrategy string
+
+const (
+	// ValidationFull downloads the archive and checks its hash.
+	ValidationFull ValidationStrategy = "full"
+
+	// ValidationSkip trusts the hash in the release metadata as is.
+	ValidationSkip ValidationStrategy = "skip"
+
+	// ValidationTrustedHash checks the hash in the release metadata against the
+	// .sha512 file published next to the archive, without downloading it.
+	ValidationTrustedHash ValidationStrategy = "trusted-hash"
+)
+
+// strength ranks the strategies by how much of the archive they check. An
+// archive validated with one strategy is trusted by any strategy of at most
+// the same strength.
+func (s ValidationStrategy) strength() int {
+	switch s {
+	case ValidationFull:
+		return 2
+	case ValidationTrustedHash:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// ParseValidationStrategy returns the strategy named by value.
+func ParseValidationStrategy(value string) (ValidationStrategy, error) {
+	switch strategy := ValidationStrategy(value); strategy {
+	case ValidationFull, ValidationSkip, ValidationTrustedHash:
+		return strategy, nil
+	default:
+		return "", fmt.Errorf("invalid validation strategy '%s': must be one of %s, %s or %s", value, ValidationFull, ValidationSkip, ValidationTrustedHash)
+	}
+}
+
+// ValidatedHashes records the archives whose hash has already been validated,
+// keyed by URL, along with the strategy that validated them, so that they are
+// not validated again. When it has a path, the records are persisted there
+// between runs.
+type ValidatedHashes struct {
+	path string
+
+	mutex  sync.Mutex
+	hashes map[string]validatedHash
+}
+
+type validatedHash struct {
+	Hash     string             `json:"hash"`
+	Strategy ValidationStrategy `json:"strategy"`
+}
+
+// NewValidatedHashes returns an empty set of records that is not persisted.
+func NewValidatedHashes() *ValidatedHashes {
+	return &ValidatedHashes{hashes: map[string]validatedHash{}}
+}
+
+// LoadValidatedHashes reads the records persisted at path. A missing file
+// holds no records.
+func LoadValidatedHashes(path string) (*ValidatedHashes, error) {
+	validated := &ValidatedHashes{path: path, hashes: map[string]validatedHash{}}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return validated, nil
+		}
+		return nil, fmt.Errorf("failed to read validated hashes: %w", err)
+	}
+
+	err = json.Unmarshal(content, &validated.hashes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse validated hashes at %s: %w", path, err)
+	}
+
+	return validated, nil
+}
+
+// Contains reports whether the archive at uri has been validated with the
+// given hash by a strategy at least as strong as the given one.
+func (v *ValidatedHashes) Contains(uri, hash string, strategy ValidationStrategy) bool {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	validated, ok := v.hashes[uri]
+	return ok && validated.Hash == hash && validated.Strategy.strength() >= strategy.strength()
+}
+
+// Add records that the archive at uri has been validated with the given hash
+// by the given strategy and persists the records.
+func (v *ValidatedHashes) Add(uri, hash string, strategy ValidationStrategy) error {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	v.hashes[uri] = validatedHash{Hash: hash, Strategy: strategy}
+	if v.path == "" {
+		return nil
+	}
+
+	content, err := json.MarshalIndent(v.hashes, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to serialize validated hashes: %w", err)
+	}
+
+	err = os.MkdirAll(filepath.Dir(v.path), os.ModePerm)
+	if err != nil {
+		return fmt.Errorf("failed to write validated hashes: %w", err)
+	}
+
+	err = os.WriteFile(v.path, content, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write validated hashes: %w", err)
+	}
+
+	return nil
+}
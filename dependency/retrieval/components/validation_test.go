@@ -0,0 +1,72 @@
+package components_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/paketo-buildpacks/dotnet-core-sdk/dependency/retrieval/components"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testValidation(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+	)
+
+	context("ParseValidationStrategy", func() {
+		it("parses each strategy", func() {
+			for _, value := range []string{"full", "skip", "trusted-hash"} {
+				strategy, err := components.ParseValidationStrategy(value)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(strategy)).To(Equal(value))
+			}
+		})
+
+		context("failure cases", func() {
+			context("when the strategy is unknown", func() {
+				it("returns an error", func() {
+					_, err := components.ParseValidationStrategy("partial")
+					Expect(err).To(MatchError("invalid validation strategy 'partial': must be one of full, skip or trusted-hash"))
+				})
+			})
+		})
+	})
+
+	context("LoadValidatedHashes", func() {
+		var path string
+
+		it.Before(func() {
+			path = filepath.Join(t.TempDir(), "validated-hashes.json")
+		})
+
+		it("persists the validated hashes", func() {
+			validated, err := components.LoadValidatedHashes(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(validated.Contains("some-url", "some-hash", components.ValidationTrustedHash)).To(BeFalse())
+
+			Expect(validated.Add("some-url", "some-hash", components.ValidationTrustedHash)).To(Succeed())
+
+			validated, err = components.LoadValidatedHashes(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(validated.Contains("some-url", "some-hash", components.ValidationTrustedHash)).To(BeTrue())
+			Expect(validated.Contains("some-url", "other-hash", components.ValidationTrustedHash)).To(BeFalse())
+			Expect(validated.Contains("some-url", "some-hash", components.ValidationFull)).To(BeFalse())
+		})
+
+		context("failure cases", func() {
+			context("when the file cannot be parsed", func() {
+				it.Before(func() {
+					Expect(os.WriteFile(path, []byte(`%%%`), 0600)).To(Succeed())
+				})
+
+				it("returns an error", func() {
+					_, err := components.LoadValidatedHashes(path)
+					Expect(err).To(MatchError(ContainSubstring("failed to parse validated hashes")))
+				})
+			})
+		})
+	})
+}
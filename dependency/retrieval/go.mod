@@ -3,6 +3,7 @@ module github.com/paketo-buildpacks/dotnet-core-sdk/dependency/retrieval
 go 1.26.6
 
 require (
+	github.com/BurntSushi/toml v1.6.0
 	github.com/Masterminds/semver/v3 v3.5.0
 	github.com/onsi/gomega v1.42.1
 	github.com/paketo-buildpacks/libdependency v0.2.1
@@ -12,7 +13,6 @@ require (
 
 require (
 	dario.cat/mergo v1.0.2 // indirect
-	github.com/BurntSushi/toml v1.6.0 // indirect
 	github.com/Microsoft/go-winio v0.6.3-0.20251027160822-ad3df93bed29 // indirect
 	github.com/ProtonMail/go-crypto v1.4.1 // indirect
 	github.com/anchore/packageurl-go v0.2.0 // indirect
@@ -1,11 +1,93 @@
 package main
 
 import (
+	"flag"
+	"path/filepath"
+	"strings"
+
 	"github.com/paketo-buildpacks/dotnet-core-sdk/dependency/retrieval/components"
 	"github.com/paketo-buildpacks/libdependency/retrieve"
+	"github.com/paketo-buildpacks/libdependency/versionology"
 )
 
 func main() {
 	fetcher := components.NewFetcher()
-	retrieve.NewMetadataWithPlatforms("dotnet-sdk", fetcher.GetVersions, components.GenerateMetadata)
+
+	// These flags are parsed along with those of retrieve
+	var cacheDir, validation, supportPhases, channels string
+	var offline bool
+	var latestPerFeatureBand int
+	flag.StringVar(&cacheDir, "cache-dir", "", "directory in which to cache the .NET release metadata and the validated checksums between runs")
+	flag.BoolVar(&offline, "offline", false, "read the .NET release metadata from the cache directory without making any requests")
+	flag.StringVar(&validation, "validation", string(components.ValidationFull), "how to validate the checksum of each SDK archive: full, skip or trusted-hash")
+	flag.StringVar(&supportPhases, "support-phases", "", "comma-separated support phases of the channels to retrieve SDKs from, e.g. active,maintenance")
+	flag.StringVar(&channels, "channels", "", "comma-separated channels to retrieve SDKs from, e.g. 8.0,9.0")
+	flag.IntVar(&latestPerFeatureBand, "latest-per-feature-band", 0, "number of latest SDKs to retrieve per feature band, or 0 for all")
+
+	// The dependencies written by retrieve cannot carry extra fields, so the
+	// output path and the fetched releases are captured to add the remaining
+	// release details to the metadata file afterwards
+	var output string
+	fetchArgs := retrieve.FetchArgs
+	retrieve.FetchArgs = func() (string, string) {
+		var buildpackTomlPath string
+		buildpackTomlPath, output = fetchArgs()
+		return buildpackTomlPath, output
+	}
+
+	// The flags are only parsed once retrieve starts, so the metadata
+	// generator is configured when the versions are fetched
+	var releases versionology.VersionFetcherArray
+	generator := components.NewMetadataGenerator()
+	getVersions := func() (versionology.VersionFetcherArray, error) {
+		strategy, err := components.ParseValidationStrategy(validation)
+		if err != nil {
+			return nil, err
+		}
+
+		validated := components.NewValidatedHashes()
+		if cacheDir != "" {
+			validated, err = components.LoadValidatedHashes(filepath.Join(cacheDir, "validated-hashes.json"))
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		generator = generator.
+			WithValidation(strategy).
+			WithValidatedHashes(validated).
+			WithOffline(offline)
+
+		releases, err = fetcher.
+			WithCache(cacheDir).
+			WithOffline(offline).
+			WithSupportPhases(splitList(supportPhases)...).
+			WithChannels(splitList(channels)...).
+			WithLatestPerFeatureBand(latestPerFeatureBand).
+			GetVersions()
+		return releases, err
+	}
+
+	generateMetadata := func(version versionology.VersionFetcher, platform retrieve.Platform) ([]versionology.Dependency, error) {
+		return generator.Generate(version, platform)
+	}
+
+	retrieve.NewMetadataWithPlatforms("dotnet-sdk", getVersions, generateMetadata)
+
+	err := components.AddReleaseMetadata(output, releases)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// splitList splits a comma-separated flag value, ignoring empty items.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
 }
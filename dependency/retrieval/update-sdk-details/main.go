@@ -0,0 +1,28 @@
+package main
+
+import (
+	"errors"
+	"flag"
+
+	"github.com/paketo-buildpacks/dotnet-core-sdk/dependency/retrieval/components"
+)
+
+// update-sdk-details records the release details from the metadata file
+// written by retrieval in the sdk-details table of buildpack.toml. It runs
+// after the update-from-metadata workflow step, which drops them from the
+// dependencies.
+func main() {
+	var buildpackTOMLPath, metadataFile string
+	flag.StringVar(&buildpackTOMLPath, "buildpack-toml-path", "", "path to the buildpack.toml file to update")
+	flag.StringVar(&metadataFile, "metadata-file", "", "path to the metadata file written by retrieval")
+	flag.Parse()
+
+	if buildpackTOMLPath == "" || metadataFile == "" {
+		panic(errors.New("--buildpack-toml-path and --metadata-file are required"))
+	}
+
+	err := components.UpdateSdkDetails(buildpackTOMLPath, metadataFile)
+	if err != nil {
+		panic(err)
+	}
+}
@@ -3,6 +3,9 @@ package dotnetcoresdk
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 
 	"github.com/Masterminds/semver"
 	"github.com/paketo-buildpacks/packit/v2"
@@ -19,45 +22,169 @@ func Detect(logger scribe.Emitter) packit.DetectFunc {
 			},
 		}
 
-		globalJson, err := FindGlobalJson(context.WorkingDir)
+		projectDir, err := ProjectDir(context.WorkingDir)
+		if err != nil {
+			return packit.DetectResult{}, err
+		}
+
+		// Files are not searched for above the application root, so that files
+		// on the build image cannot decide the SDK version. Searching above it
+		// for global.json can be opted into.
+		searchRoot := context.WorkingDir
+
+		globalJsonRoot := searchRoot
+		if value, ok := os.LookupEnv(GlobalJsonSearchAboveRoot); ok {
+			searchAbove, err := strconv.ParseBool(value)
+			if err != nil {
+				return packit.DetectResult{}, fmt.Errorf("invalid value '%s' for %s: must be true or false", value, GlobalJsonSearchAboveRoot)
+			}
+
+			if searchAbove {
+				globalJsonRoot = ""
+			}
+		}
+
+		globalJson, err := FindGlobalJson(projectDir, globalJsonRoot)
 		if err != nil {
 			return packit.DetectResult{}, err
 		}
 		if globalJson != nil && globalJson.Sdk != nil && globalJson.Sdk.Version != nil {
 			version, err := semver.NewVersion(*globalJson.Sdk.Version)
 			if err != nil {
-				return packit.DetectResult{}, err
+				return packit.DetectResult{}, InvalidVersionError{File: "global.json", Field: "sdk.version", Version: *globalJson.Sdk.Version, Err: err}
 			}
 
 			rollForward := "patch"
 			if globalJson.Sdk.RollForward != nil {
-				rollForward = *globalJson.Sdk.RollForward
+				rollForward, err = NormalizeRollForward(*globalJson.Sdk.RollForward)
+				if err != nil {
+					return packit.DetectResult{}, err
+				}
+			}
+
+			metadata := map[string]interface{}{
+				"version":          version.String(),
+				"version-source":   "global.json",
+				"roll-forward":     rollForward,
+				"global-json-path": globalJson.Path,
+			}
+			if globalJson.Sdk.AllowPrerelease != nil {
+				metadata["allow-prerelease"] = *globalJson.Sdk.AllowPrerelease
+			}
+			if paths := globalJson.ResolvedPaths(); len(paths) > 0 {
+				metadata["sdk-paths"] = paths
+			}
+			if globalJson.Sdk.ErrorMessage != nil {
+				metadata["error-message"] = *globalJson.Sdk.ErrorMessage
+			}
+			if len(globalJson.MsbuildSdks) > 0 {
+				metadata["msbuild-sdks"] = globalJson.MsbuildSdks
 			}
 
+			plan.Requires = append(plan.Requires, packit.BuildPlanRequirement{
+				Name:     "dotnet-sdk",
+				Metadata: metadata,
+			})
+		} else if globalJson != nil && len(globalJson.MsbuildSdks) > 0 {
+			// A global.json that only declares msbuild-sdks, as is usual for
+			// Microsoft.Build.Traversal, does not select an SDK version, but its
+			// msbuild-sdks are still passed on
 			plan.Requires = append(plan.Requires, packit.BuildPlanRequirement{
 				Name: "dotnet-sdk",
 				Metadata: map[string]interface{}{
-					"version":        version.String(),
-					"version-source": "global.json",
-					"roll-forward":   rollForward,
+					"msbuild-sdks": globalJson.MsbuildSdks,
 				},
 			})
 		}
 
-		if sdkVersion, ok := os.LookupEnv(DotnetSdkVersion); ok {
-			_, err := semver.NewConstraint(sdkVersion)
+		projectFiles, err := FindProjectFiles(projectDir)
+		if err != nil {
+			return packit.DetectResult{}, err
+		}
+		for _, projectFilePath := range projectFiles {
+			projectFile, err := ParseProjectFile(projectFilePath, searchRoot)
 			if err != nil {
 				return packit.DetectResult{}, err
 			}
+
+			constraint, ok := GetSdkConstraintFromTargetFrameworks(projectFile.TargetFrameworks())
+			if !ok {
+				continue
+			}
+
 			plan.Requires = append(plan.Requires, packit.BuildPlanRequirement{
 				Name: "dotnet-sdk",
 				Metadata: map[string]interface{}{
-					"version-source": DotnetSdkVersion,
-					"version":        sdkVersion,
+					"version":        constraint,
+					"version-source": filepath.Base(projectFilePath),
 				},
 			})
 		}
 
+		propsPath, err := FindDirectoryBuildProps(projectDir, searchRoot)
+		if err != nil {
+			return packit.DetectResult{}, err
+		}
+		if propsPath != "" {
+			props, err := ParseDirectoryBuildProps(propsPath)
+			if err != nil {
+				return packit.DetectResult{}, err
+			}
+
+			constraint, ok := GetSdkConstraintFromTargetFrameworks(props.TargetFrameworks())
+			if ok {
+				plan.Requires = append(plan.Requires, packit.BuildPlanRequirement{
+					Name: "dotnet-sdk",
+					Metadata: map[string]interface{}{
+						"version":        constraint,
+						"version-source": DirectoryBuildProps,
+					},
+				})
+			}
+		}
+
+		runtimeConfigPath, err := FindRuntimeConfig(projectDir)
+		if err != nil {
+			return packit.DetectResult{}, err
+		}
+		if runtimeConfigPath != "" {
+			runtimeConfig, err := ParseRuntimeConfig(runtimeConfigPath)
+			if err != nil {
+				return packit.DetectResult{}, err
+			}
+
+			constraint, ok, err := runtimeConfig.GetSdkConstraint()
+			if err != nil {
+				return packit.DetectResult{}, err
+			}
+
+			if ok {
+				plan.Requires = append(plan.Requires, packit.BuildPlanRequirement{
+					Name: "dotnet-sdk",
+					Metadata: map[string]interface{}{
+						"version":        constraint,
+						"version-source": "runtimeconfig.json",
+					},
+				})
+			}
+		}
+
+		if sdkVersions, ok := os.LookupEnv(DotnetSdkVersion); ok {
+			for _, sdkVersion := range splitSdkVersions(sdkVersions) {
+				_, err := semver.NewConstraint(sdkVersion)
+				if err != nil {
+					return packit.DetectResult{}, err
+				}
+				plan.Requires = append(plan.Requires, packit.BuildPlanRequirement{
+					Name: "dotnet-sdk",
+					Metadata: map[string]interface{}{
+						"version-source": DotnetSdkVersion,
+						"version":        sdkVersion,
+					},
+				})
+			}
+		}
+
 		if frameworkVersion, ok := os.LookupEnv(DeprecatedFrameworkVersion); ok {
 			logger.Subprocess(scribe.YellowColor("WARNING: BP_DOTNET_FRAMEWORK_VERSION is deprecated and will be removed in a future version. Please use global.json or BP_DOTNET_SDK_VERSION to select SDK version instead."))
 			frameworkSemver, err := semver.NewVersion(frameworkVersion)
@@ -76,3 +203,25 @@ func Detect(logger scribe.Emitter) packit.DetectFunc {
 		return packit.DetectResult{Plan: plan}, nil
 	}
 }
+
+// splitSdkVersions splits a list-valued BP_DOTNET_SDK_VERSION such as
+// "8.0.*,9.0.*" into one version per SDK. A comma also joins the parts of a
+// single range constraint (e.g. ">= 8.0.100, < 8.0.200"), so the value is only
+// treated as a list when none of its parts use a comparison operator.
+func splitSdkVersions(value string) []string {
+	parts := strings.Split(value, ",")
+	if len(parts) == 1 {
+		return []string{value}
+	}
+
+	var versions []string
+	for _, part := range parts {
+		part = strings.TrimSpace(part)
+		if part == "" || strings.ContainsAny(part, "<>=~^!") {
+			return []string{value}
+		}
+		versions = append(versions, part)
+	}
+
+	return versions
+}
@@ -2,6 +2,7 @@ package dotnetcoresdk_test
 
 import (
 	"bytes"
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
@@ -101,6 +102,63 @@ func testDetect(t *testing.T, context spec.G, it spec.S) {
 		})
 	})
 
+	context("when a list of versions is specified via BP_DOTNET_SDK_VERSION", func() {
+		it.Before(func() {
+			Expect(os.Setenv("BP_DOTNET_SDK_VERSION", "8.0.*, 9.0.*")).To(Succeed())
+		})
+		it.After(func() {
+			Expect(os.Unsetenv("BP_DOTNET_SDK_VERSION")).To(Succeed())
+		})
+
+		it("requires each version of the SDK specified in the variable", func() {
+			result, err := detect(packit.DetectContext{
+				WorkingDir: "working-dir",
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Plan.Requires).To(Equal([]packit.BuildPlanRequirement{
+				{
+					Name: "dotnet-sdk",
+					Metadata: map[string]interface{}{
+						"version":        "8.0.*",
+						"version-source": "BP_DOTNET_SDK_VERSION",
+					},
+				},
+				{
+					Name: "dotnet-sdk",
+					Metadata: map[string]interface{}{
+						"version":        "9.0.*",
+						"version-source": "BP_DOTNET_SDK_VERSION",
+					},
+				},
+			}))
+		})
+	})
+
+	context("when a range constraint is specified via BP_DOTNET_SDK_VERSION", func() {
+		it.Before(func() {
+			Expect(os.Setenv("BP_DOTNET_SDK_VERSION", ">= 8.0.100, < 8.0.200")).To(Succeed())
+		})
+		it.After(func() {
+			Expect(os.Unsetenv("BP_DOTNET_SDK_VERSION")).To(Succeed())
+		})
+
+		it("requires a single SDK matching the whole constraint", func() {
+			result, err := detect(packit.DetectContext{
+				WorkingDir: "working-dir",
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Plan.Requires).To(Equal([]packit.BuildPlanRequirement{
+				{
+					Name: "dotnet-sdk",
+					Metadata: map[string]interface{}{
+						"version":        ">= 8.0.100, < 8.0.200",
+						"version-source": "BP_DOTNET_SDK_VERSION",
+					},
+				},
+			}))
+		})
+	})
+
 	context("when a global.json file is provided", func() {
 		it("requires the version specified in the global.json file", func() {
 			tempDir := t.TempDir()
@@ -124,9 +182,452 @@ func testDetect(t *testing.T, context spec.G, it spec.S) {
 					{
 						Name: "dotnet-sdk",
 						Metadata: map[string]interface{}{
-							"version":        "7.0.203",
-							"version-source": "global.json",
-							"roll-forward":   "patch",
+							"version":          "7.0.203",
+							"version-source":   "global.json",
+							"roll-forward":     "patch",
+							"global-json-path": filepath.Join(tempDir, "global.json"),
+						},
+					},
+				},
+			}))
+		})
+
+		it("normalizes the case of the rollForward value", func() {
+			tempDir := t.TempDir()
+			err := os.WriteFile(filepath.Join(tempDir, "global.json"), []byte(`{
+				"sdk": {
+					"version": "8.0.100",
+					"rollForward": "LatestMinor"
+				}
+			}`), 0644)
+			Expect(err).NotTo(HaveOccurred())
+
+			result, err := detect(packit.DetectContext{
+				WorkingDir: tempDir,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Plan.Requires).To(HaveLen(1))
+			Expect(result.Plan.Requires[0].Metadata).To(HaveKeyWithValue("roll-forward", "latestMinor"))
+		})
+
+		it("passes the sdk paths and error message through to the build plan", func() {
+			tempDir := t.TempDir()
+			err := os.WriteFile(filepath.Join(tempDir, "global.json"), []byte(`{
+				"sdk": {
+					"version": "10.0.100",
+					"paths": [".dotnet", "$host$"],
+					"errorMessage": "Run ./install-sdk.sh"
+				}
+			}`), 0644)
+			Expect(err).NotTo(HaveOccurred())
+
+			result, err := detect(packit.DetectContext{
+				WorkingDir: tempDir,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Plan.Requires).To(Equal([]packit.BuildPlanRequirement{
+				{
+					Name: "dotnet-sdk",
+					Metadata: map[string]interface{}{
+						"version":          "10.0.100",
+						"version-source":   "global.json",
+						"roll-forward":     "patch",
+						"global-json-path": filepath.Join(tempDir, "global.json"),
+						"sdk-paths":        []string{filepath.Join(tempDir, ".dotnet"), "$host$"},
+						"error-message":    "Run ./install-sdk.sh",
+					},
+				},
+			}))
+		})
+
+		it("passes the msbuild-sdks through to the build plan", func() {
+			tempDir := t.TempDir()
+			err := os.WriteFile(filepath.Join(tempDir, "global.json"), []byte(`{
+				"sdk": {
+					"version": "8.0.100"
+				},
+				"msbuild-sdks": {
+					"Microsoft.Build.Traversal": "4.1.0"
+				}
+			}`), 0644)
+			Expect(err).NotTo(HaveOccurred())
+
+			result, err := detect(packit.DetectContext{
+				WorkingDir: tempDir,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Plan.Requires).To(HaveLen(1))
+			Expect(result.Plan.Requires[0].Metadata).To(HaveKeyWithValue("msbuild-sdks", map[string]string{
+				"Microsoft.Build.Traversal": "4.1.0",
+			}))
+		})
+
+		it("passes the msbuild-sdks through to the build plan when there is no sdk section", func() {
+			tempDir := t.TempDir()
+			err := os.WriteFile(filepath.Join(tempDir, "global.json"), []byte(`{
+				"msbuild-sdks": {
+					"Microsoft.Build.Traversal": "4.1.0"
+				}
+			}`), 0644)
+			Expect(err).NotTo(HaveOccurred())
+
+			result, err := detect(packit.DetectContext{
+				WorkingDir: tempDir,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Plan.Requires).To(Equal([]packit.BuildPlanRequirement{
+				{
+					Name: "dotnet-sdk",
+					Metadata: map[string]interface{}{
+						"msbuild-sdks": map[string]string{
+							"Microsoft.Build.Traversal": "4.1.0",
+						},
+					},
+				},
+			}))
+		})
+
+		it("passes the allowPrerelease flag through to the build plan", func() {
+			tempDir := t.TempDir()
+			err := os.WriteFile(filepath.Join(tempDir, "global.json"), []byte(`{
+				"sdk": {
+					"version": "10.0.100",
+					"rollForward": "latestFeature",
+					"allowPrerelease": true
+				}
+			}`), 0644)
+			Expect(err).NotTo(HaveOccurred())
+
+			result, err := detect(packit.DetectContext{
+				WorkingDir: tempDir,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Plan.Requires).To(Equal([]packit.BuildPlanRequirement{
+				{
+					Name: "dotnet-sdk",
+					Metadata: map[string]interface{}{
+						"version":          "10.0.100",
+						"version-source":   "global.json",
+						"roll-forward":     "latestFeature",
+						"global-json-path": filepath.Join(tempDir, "global.json"),
+						"allow-prerelease": true,
+					},
+				},
+			}))
+		})
+	})
+
+	context("when a global.json file is above the application root", func() {
+		var workingDir string
+
+		it.Before(func() {
+			parentDir := t.TempDir()
+			Expect(os.WriteFile(filepath.Join(parentDir, "global.json"), []byte(`{"sdk": {"version": "6.0.100"}}`), 0644)).To(Succeed())
+
+			workingDir = filepath.Join(parentDir, "workspace")
+			Expect(os.MkdirAll(workingDir, os.ModePerm)).To(Succeed())
+		})
+
+		it("ignores it", func() {
+			result, err := detect(packit.DetectContext{
+				WorkingDir: workingDir,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Plan.Requires).To(BeEmpty())
+		})
+
+		context("when BP_DOTNET_GLOBAL_JSON_SEARCH_ABOVE_ROOT is set", func() {
+			it.Before(func() {
+				Expect(os.Setenv("BP_DOTNET_GLOBAL_JSON_SEARCH_ABOVE_ROOT", "true")).To(Succeed())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_DOTNET_GLOBAL_JSON_SEARCH_ABOVE_ROOT")).To(Succeed())
+			})
+
+			it("uses it", func() {
+				result, err := detect(packit.DetectContext{
+					WorkingDir: workingDir,
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.Plan.Requires).To(HaveLen(1))
+				Expect(result.Plan.Requires[0].Metadata).To(HaveKeyWithValue("version", "6.0.100"))
+				Expect(result.Plan.Requires[0].Metadata).To(HaveKeyWithValue("global-json-path", filepath.Join(filepath.Dir(workingDir), "global.json")))
+			})
+		})
+	})
+
+	context("when a project file with a target framework is provided", func() {
+		var workingDir string
+
+		it.Before(func() {
+			workingDir = t.TempDir()
+			err := os.WriteFile(filepath.Join(workingDir, "app.csproj"), []byte(`<Project Sdk="Microsoft.NET.Sdk.Web">
+				<PropertyGroup>
+					<TargetFrameworks>net8.0;net9.0</TargetFrameworks>
+				</PropertyGroup>
+			</Project>`), 0644)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		it("requires the SDK version matching the highest target framework", func() {
+			result, err := detect(packit.DetectContext{
+				WorkingDir: workingDir,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Plan).To(Equal(packit.BuildPlan{
+				Provides: []packit.BuildPlanProvision{
+					{Name: "dotnet-sdk"},
+				},
+				Requires: []packit.BuildPlanRequirement{
+					{
+						Name: "dotnet-sdk",
+						Metadata: map[string]interface{}{
+							"version":        "9.0.*",
+							"version-source": "app.csproj",
+						},
+					},
+				},
+			}))
+		})
+	})
+
+	context("when the target framework is set in Directory.Build.props", func() {
+		var workingDir string
+
+		it.Before(func() {
+			workingDir = t.TempDir()
+			Expect(os.WriteFile(filepath.Join(workingDir, "Directory.Build.props"), []byte(`<Project>
+				<PropertyGroup>
+					<DotnetVersion>8.0</DotnetVersion>
+					<TargetFramework>net$(DotnetVersion)</TargetFramework>
+				</PropertyGroup>
+			</Project>`), 0644)).To(Succeed())
+
+			Expect(os.MkdirAll(filepath.Join(workingDir, "src", "app"), os.ModePerm)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(workingDir, "src", "app", "app.csproj"), []byte(`<Project Sdk="Microsoft.NET.Sdk">
+				<PropertyGroup>
+					<OutputType>Exe</OutputType>
+				</PropertyGroup>
+			</Project>`), 0644)).To(Succeed())
+		})
+
+		it("requires the SDK version matching the target framework in Directory.Build.props", func() {
+			result, err := detect(packit.DetectContext{
+				WorkingDir: workingDir,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Plan).To(Equal(packit.BuildPlan{
+				Provides: []packit.BuildPlanProvision{
+					{Name: "dotnet-sdk"},
+				},
+				Requires: []packit.BuildPlanRequirement{
+					{
+						Name: "dotnet-sdk",
+						Metadata: map[string]interface{}{
+							"version":        "8.0.*",
+							"version-source": "Directory.Build.props",
+						},
+					},
+				},
+			}))
+		})
+
+		context("when BP_DOTNET_PROJECT_PATH selects a project below it", func() {
+			it.Before(func() {
+				Expect(os.Setenv("BP_DOTNET_PROJECT_PATH", "src/app")).To(Succeed())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_DOTNET_PROJECT_PATH")).To(Succeed())
+			})
+
+			it("requires the SDK version for the project, which inherits the target framework", func() {
+				result, err := detect(packit.DetectContext{
+					WorkingDir: workingDir,
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.Plan.Requires).To(Equal([]packit.BuildPlanRequirement{
+					{
+						Name: "dotnet-sdk",
+						Metadata: map[string]interface{}{
+							"version":        "8.0.*",
+							"version-source": "app.csproj",
+						},
+					},
+					{
+						Name: "dotnet-sdk",
+						Metadata: map[string]interface{}{
+							"version":        "8.0.*",
+							"version-source": "Directory.Build.props",
+						},
+					},
+				}))
+			})
+		})
+
+		context("when the working directory is a project below it", func() {
+			it("ignores the Directory.Build.props above the application root", func() {
+				result, err := detect(packit.DetectContext{
+					WorkingDir: filepath.Join(workingDir, "src", "app"),
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.Plan.Requires).To(BeEmpty())
+			})
+		})
+	})
+
+	context("when BP_DOTNET_PROJECT_PATH selects a project in a subdirectory", func() {
+		var workingDir string
+
+		it.Before(func() {
+			workingDir = t.TempDir()
+			for _, app := range []struct{ dir, version, tfm string }{
+				{"api", "8.0.100", "net8.0"},
+				{"web", "9.0.200", "net9.0"},
+			} {
+				Expect(os.MkdirAll(filepath.Join(workingDir, "apps", app.dir), os.ModePerm)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(workingDir, "apps", app.dir, "global.json"), []byte(fmt.Sprintf(`{"sdk": {"version": %q}}`, app.version)), 0644)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(workingDir, "apps", app.dir, app.dir+".csproj"), []byte(fmt.Sprintf(`<Project Sdk="Microsoft.NET.Sdk">
+					<PropertyGroup>
+						<TargetFramework>%s</TargetFramework>
+					</PropertyGroup>
+				</Project>`, app.tfm)), 0644)).To(Succeed())
+			}
+
+			Expect(os.Setenv("BP_DOTNET_PROJECT_PATH", "apps/web")).To(Succeed())
+		})
+
+		it.After(func() {
+			Expect(os.Unsetenv("BP_DOTNET_PROJECT_PATH")).To(Succeed())
+		})
+
+		it("uses the global.json and project file of that project", func() {
+			result, err := detect(packit.DetectContext{
+				WorkingDir: workingDir,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Plan.Requires).To(Equal([]packit.BuildPlanRequirement{
+				{
+					Name: "dotnet-sdk",
+					Metadata: map[string]interface{}{
+						"version":          "9.0.200",
+						"version-source":   "global.json",
+						"roll-forward":     "patch",
+						"global-json-path": filepath.Join(workingDir, "apps", "web", "global.json"),
+					},
+				},
+				{
+					Name: "dotnet-sdk",
+					Metadata: map[string]interface{}{
+						"version":        "9.0.*",
+						"version-source": "web.csproj",
+					},
+				},
+			}))
+		})
+
+		context("when there are runtimeconfig.json files at the application root and in the project", func() {
+			it.Before(func() {
+				for _, file := range []struct{ path, version string }{
+					{filepath.Join(workingDir, "root.runtimeconfig.json"), "6.0.36"},
+					{filepath.Join(workingDir, "apps", "web", "web.runtimeconfig.json"), "9.0.4"},
+				} {
+					Expect(os.WriteFile(file.path, []byte(fmt.Sprintf(`{
+						"runtimeOptions": {
+							"framework": {"name": "Microsoft.NETCore.App", "version": %q}
+						}
+					}`, file.version)), 0644)).To(Succeed())
+				}
+			})
+
+			it("uses the runtimeconfig.json of that project", func() {
+				result, err := detect(packit.DetectContext{
+					WorkingDir: workingDir,
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.Plan.Requires).To(ContainElement(packit.BuildPlanRequirement{
+					Name: "dotnet-sdk",
+					Metadata: map[string]interface{}{
+						"version":        "9.0.*",
+						"version-source": "runtimeconfig.json",
+					},
+				}))
+				Expect(result.Plan.Requires).NotTo(ContainElement(HaveField("Metadata", HaveKeyWithValue("version", "6.0.*"))))
+			})
+		})
+
+		context("when the project has no global.json", func() {
+			it.Before(func() {
+				Expect(os.Remove(filepath.Join(workingDir, "apps", "web", "global.json"))).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(workingDir, "global.json"), []byte(`{"sdk": {"version": "8.0.300"}}`), 0644)).To(Succeed())
+			})
+
+			it("finds the global.json at the application root", func() {
+				result, err := detect(packit.DetectContext{
+					WorkingDir: workingDir,
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.Plan.Requires[0].Metadata).To(HaveKeyWithValue("version", "8.0.300"))
+			})
+		})
+
+		context("when the only global.json is above the application root", func() {
+			it.Before(func() {
+				Expect(os.Remove(filepath.Join(workingDir, "apps", "web", "global.json"))).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(workingDir, "global.json"), []byte(`{"sdk": {"version": "8.0.300"}}`), 0644)).To(Succeed())
+				Expect(os.Setenv("BP_DOTNET_PROJECT_PATH", "web")).To(Succeed())
+			})
+
+			it("ignores it", func() {
+				result, err := detect(packit.DetectContext{
+					WorkingDir: filepath.Join(workingDir, "apps"),
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.Plan.Requires).To(Equal([]packit.BuildPlanRequirement{
+					{
+						Name: "dotnet-sdk",
+						Metadata: map[string]interface{}{
+							"version":        "9.0.*",
+							"version-source": "web.csproj",
+						},
+					},
+				}))
+			})
+		})
+	})
+
+	context("when a runtimeconfig.json file is provided", func() {
+		var workingDir string
+
+		it.Before(func() {
+			workingDir = t.TempDir()
+			err := os.WriteFile(filepath.Join(workingDir, "app.runtimeconfig.json"), []byte(`{
+				"runtimeOptions": {
+					"framework": {
+						"name": "Microsoft.AspNetCore.App",
+						"version": "8.0.11"
+					}
+				}
+			}`), 0644)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		it("requires the SDK version matching the runtime major.minor", func() {
+			result, err := detect(packit.DetectContext{
+				WorkingDir: workingDir,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Plan).To(Equal(packit.BuildPlan{
+				Provides: []packit.BuildPlanProvision{
+					{Name: "dotnet-sdk"},
+				},
+				Requires: []packit.BuildPlanRequirement{
+					{
+						Name: "dotnet-sdk",
+						Metadata: map[string]interface{}{
+							"version":        "8.0.*",
+							"version-source": "runtimeconfig.json",
 						},
 					},
 				},
@@ -135,6 +636,113 @@ func testDetect(t *testing.T, context spec.G, it spec.S) {
 	})
 
 	context("failure cases", func() {
+		context("when BP_DOTNET_GLOBAL_JSON_SEARCH_ABOVE_ROOT is not a boolean", func() {
+			it.Before(func() {
+				Expect(os.Setenv("BP_DOTNET_GLOBAL_JSON_SEARCH_ABOVE_ROOT", "sometimes")).To(Succeed())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_DOTNET_GLOBAL_JSON_SEARCH_ABOVE_ROOT")).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := detect(packit.DetectContext{
+					WorkingDir: t.TempDir(),
+				})
+				Expect(err).To(MatchError("invalid value 'sometimes' for BP_DOTNET_GLOBAL_JSON_SEARCH_ABOVE_ROOT: must be true or false"))
+			})
+		})
+
+		context("when BP_DOTNET_PROJECT_PATH is outside the application", func() {
+			it.Before(func() {
+				Expect(os.Setenv("BP_DOTNET_PROJECT_PATH", "../other-app")).To(Succeed())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_DOTNET_PROJECT_PATH")).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := detect(packit.DetectContext{
+					WorkingDir: t.TempDir(),
+				})
+				Expect(err).To(MatchError("invalid value '../other-app' for BP_DOTNET_PROJECT_PATH: must be a directory inside the application"))
+			})
+		})
+
+		context("when the global.json SDK version is malformed", func() {
+			var workingDir string
+
+			it.Before(func() {
+				workingDir = t.TempDir()
+				Expect(os.WriteFile(filepath.Join(workingDir, "global.json"), []byte(`{
+					"sdk": {
+						"version": "eight"
+					}
+				}`), 0644)).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := detect(packit.DetectContext{
+					WorkingDir: workingDir,
+				})
+				Expect(err).To(MatchError(ContainSubstring("invalid version 'eight' for sdk.version in global.json")))
+			})
+		})
+
+		context("when the global.json rollForward value is invalid", func() {
+			var workingDir string
+
+			it.Before(func() {
+				workingDir = t.TempDir()
+				Expect(os.WriteFile(filepath.Join(workingDir, "global.json"), []byte(`{
+					"sdk": {
+						"version": "8.0.100",
+						"rollForward": "lastestMinor"
+					}
+				}`), 0644)).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := detect(packit.DetectContext{
+					WorkingDir: workingDir,
+				})
+				Expect(err).To(MatchError(ContainSubstring("invalid rollForward value 'lastestMinor' in global.json")))
+			})
+		})
+
+		context("when a runtimeconfig.json cannot be parsed", func() {
+			var workingDir string
+
+			it.Before(func() {
+				workingDir = t.TempDir()
+				Expect(os.WriteFile(filepath.Join(workingDir, "app.runtimeconfig.json"), []byte(`{`), 0644)).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := detect(packit.DetectContext{
+					WorkingDir: workingDir,
+				})
+				Expect(err).To(MatchError(ContainSubstring("failed to parse app.runtimeconfig.json")))
+			})
+		})
+
+		context("when a project file cannot be parsed", func() {
+			var workingDir string
+
+			it.Before(func() {
+				workingDir = t.TempDir()
+				Expect(os.WriteFile(filepath.Join(workingDir, "app.fsproj"), []byte(`<Project`), 0644)).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := detect(packit.DetectContext{
+					WorkingDir: workingDir,
+				})
+				Expect(err).To(MatchError(ContainSubstring("failed to parse app.fsproj")))
+			})
+		})
+
 		context("BP_DOTNET_FRAMEWORK_VERSION is not a semantic version", func() {
 			it.Before(func() {
 				Expect(os.Setenv("BP_DOTNET_FRAMEWORK_VERSION", "bad-version")).To(Succeed())
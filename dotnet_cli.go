@@ -0,0 +1,60 @@
+package dotnetcoresdk
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/paketo-buildpacks/packit/v2/pexec"
+	"github.com/paketo-buildpacks/packit/v2/scribe"
+)
+
+// DotnetExecutable runs the dotnet host of an SDK installation.
+type DotnetExecutable struct {
+	logger scribe.Emitter
+}
+
+func NewDotnetExecutable(logger scribe.Emitter) DotnetExecutable {
+	return DotnetExecutable{
+		logger: logger,
+	}
+}
+
+// Execute runs the dotnet host found in dotnetRoot with the given arguments
+// and the additional environment variables in env, given as KEY=VALUE. The
+// output is only printed when the command fails.
+func (d DotnetExecutable) Execute(dotnetRoot, workingDir string, env []string, args ...string) error {
+	environment := append(os.Environ(),
+		fmt.Sprintf("DOTNET_ROOT=%s", dotnetRoot),
+		fmt.Sprintf("PATH=%s%c%s", dotnetRoot, os.PathListSeparator, os.Getenv("PATH")),
+	)
+	for _, variable := range CliEnvironmentDefaults {
+		if _, ok := os.LookupEnv(variable.Name); ok {
+			continue
+		}
+
+		value, ok := os.LookupEnv(variable.Setting)
+		if !ok {
+			value = variable.Default
+		}
+		environment = append(environment, fmt.Sprintf("%s=%s", variable.Name, value))
+	}
+	environment = append(environment, env...)
+
+	buffer := bytes.NewBuffer(nil)
+	err := pexec.NewExecutable(filepath.Join(dotnetRoot, "dotnet")).Execute(pexec.Execution{
+		Args:   args,
+		Dir:    workingDir,
+		Env:    environment,
+		Stdout: buffer,
+		Stderr: buffer,
+	})
+	if err != nil {
+		d.logger.Detail(buffer.String())
+		return fmt.Errorf("failed to execute 'dotnet %s': %w", strings.Join(args, " "), err)
+	}
+
+	return nil
+}
@@ -1,31 +1,94 @@
 package dotnetcoresdk
 
 import (
-	"encoding/json"
 	"fmt"
-	"io"
 	"os"
-	"path"
 	"path/filepath"
 	"slices"
+	"strings"
 
 	"github.com/Masterminds/semver/v3"
 )
 
+// HostSdkPath is the sdk.paths entry that refers to the SDKs installed
+// alongside the dotnet host, i.e. the SDK provided by this buildpack.
+const HostSdkPath = "$host$"
+
 type GlobalJson struct {
 	Sdk *Sdk `json:"sdk,omitempty"`
+
+	// MsbuildSdks maps the IDs of MSBuild project SDK packages, such as
+	// Microsoft.Build.Traversal, to their versions
+	MsbuildSdks map[string]string `json:"msbuild-sdks,omitempty"`
+
+	// Path is the location of the parsed global.json file
+	Path string `json:"-"`
 }
 
 type Sdk struct {
-	Version         *string `json:"version,omitempty"`
-	AllowPrerelease *bool   `json:"allowPrerelease,omitempty"`
-	RollForward     *string `json:"rollForward,omitempty"`
+	Version         *string  `json:"version,omitempty"`
+	AllowPrerelease *bool    `json:"allowPrerelease,omitempty"`
+	RollForward     *string  `json:"rollForward,omitempty"`
+	Paths           []string `json:"paths,omitempty"`
+	ErrorMessage    *string  `json:"errorMessage,omitempty"`
+}
+
+// ResolvedPaths returns the sdk.paths entries with relative paths resolved
+// against the directory containing the global.json file.
+func (g GlobalJson) ResolvedPaths() []string {
+	if g.Sdk == nil {
+		return nil
+	}
+
+	var paths []string
+	for _, p := range g.Sdk.Paths {
+		if p != HostSdkPath && !filepath.IsAbs(p) {
+			p = filepath.Join(filepath.Dir(g.Path), p)
+		}
+		paths = append(paths, p)
+	}
+
+	return paths
+}
+
+// RollForwardPolicies lists the rollForward values accepted in global.json
+// https://learn.microsoft.com/en-us/dotnet/core/tools/global-json#rollforward
+var RollForwardPolicies = []string{
+	"patch",
+	"feature",
+	"minor",
+	"major",
+	"latestPatch",
+	"latestFeature",
+	"latestMinor",
+	"latestMajor",
+	"disabled",
+}
+
+// NormalizeRollForward returns the canonical spelling of the given rollForward
+// value. Like the .NET host, values are compared case-insensitively.
+func NormalizeRollForward(rollForward string) (string, error) {
+	for _, policy := range RollForwardPolicies {
+		if strings.EqualFold(policy, rollForward) {
+			return policy, nil
+		}
+	}
+
+	return "", fmt.Errorf("invalid rollForward value '%s' in global.json: must be one of [%s]", rollForward, strings.Join(RollForwardPolicies, ", "))
 }
 
 func GetRollforwardConstraints(versionStr string, rollForward string) ([]string, error) {
 	results := []string{}
 
-	version := semver.MustParse(versionStr)
+	rollForward, err := NormalizeRollForward(rollForward)
+	if err != nil {
+		return nil, err
+	}
+
+	version, err := semver.StrictNewVersion(versionStr)
+	if err != nil {
+		return nil, InvalidVersionError{File: "global.json", Field: "sdk.version", Version: versionStr, Err: err}
+	}
 	featureLevel := version.Patch() / 100
 
 	// Refer to the documentation on rollForward behaviour
@@ -85,35 +148,28 @@ func GetRollforwardConstraints(versionStr string, rollForward string) ([]string,
 	return results, nil
 }
 
-func FindGlobalJson(dir string) (*GlobalJson, error) {
-	filePath := path.Join(dir, "global.json")
-	if _, err := os.Stat(filePath); err == nil {
-		jsonFile, err := os.Open(filePath)
-		if err != nil {
-			return nil, fmt.Errorf("failed to open global.json: %w", err)
-		}
-
-		fileContents, err := io.ReadAll(jsonFile)
-		if err != nil {
-			return nil, fmt.Errorf("failed to read global.json: %w", err)
-		}
-
-		var globalJson GlobalJson
-		err = json.Unmarshal(fileContents, &globalJson)
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse global.json: %w", err)
-		}
+// FindGlobalJson looks for global.json in the given directory and its
+// parents, up to the root directory (see findUp). It returns nil if there is
+// no global.json.
+func FindGlobalJson(dir, root string) (*GlobalJson, error) {
+	filePath, err := findUp(dir, root, "global.json")
+	if err != nil || filePath == "" {
+		return nil, err
+	}
 
-		return &globalJson, nil
+	fileContents, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read global.json: %w", err)
 	}
 
-	parentDir := filepath.Dir(dir)
-	if dir == parentDir {
-		return nil, nil
+	var globalJson GlobalJson
+	err = unmarshalRelaxedJSON(fileContents, &globalJson)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse global.json: %w", err)
 	}
+	globalJson.Path = filePath
 
-	// Recurse up the tree to try find global.json
-	return FindGlobalJson(parentDir)
+	return &globalJson, nil
 }
 
 func getPatchForFeatureLevel(featureLevel uint64) uint64 {
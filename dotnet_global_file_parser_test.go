@@ -1,6 +1,7 @@
 package dotnetcoresdk_test
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
@@ -29,7 +30,7 @@ func testGlobalFileParser(t *testing.T, context spec.G, it spec.S) {
 			}`), 0644)
 			Expect(err).NotTo(HaveOccurred())
 
-			globalJson, err := dotnetcoresdk.FindGlobalJson(tempDir)
+			globalJson, err := dotnetcoresdk.FindGlobalJson(tempDir, "")
 			Expect(err).NotTo(HaveOccurred())
 			Expect(globalJson).NotTo(BeNil())
 			Expect(globalJson.Sdk).NotTo(BeNil())
@@ -38,6 +39,29 @@ func testGlobalFileParser(t *testing.T, context spec.G, it spec.S) {
 			Expect(*globalJson.Sdk.AllowPrerelease).To(BeFalse())
 		})
 
+		it("parses the sdk paths and error message", func() {
+			tempDir := t.TempDir()
+			err := os.WriteFile(filepath.Join(tempDir, "global.json"), []byte(`{
+				"sdk": {
+					"version": "10.0.100",
+					"paths": [".dotnet", "$host$", "/opt/dotnet"],
+					"errorMessage": "Run ./install-sdk.sh first"
+				}
+			}`), 0644)
+			Expect(err).NotTo(HaveOccurred())
+
+			globalJson, err := dotnetcoresdk.FindGlobalJson(tempDir, "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(globalJson.Path).To(Equal(filepath.Join(tempDir, "global.json")))
+			Expect(globalJson.Sdk.Paths).To(Equal([]string{".dotnet", "$host$", "/opt/dotnet"}))
+			Expect(*globalJson.Sdk.ErrorMessage).To(Equal("Run ./install-sdk.sh first"))
+			Expect(globalJson.ResolvedPaths()).To(Equal([]string{
+				filepath.Join(tempDir, ".dotnet"),
+				"$host$",
+				"/opt/dotnet",
+			}))
+		})
+
 		it("finds global.json in parent directories", func() {
 			tempDir := t.TempDir()
 			subDirs := filepath.Join(tempDir, "subdir1", "subdir2")
@@ -52,17 +76,35 @@ func testGlobalFileParser(t *testing.T, context spec.G, it spec.S) {
 			}`), 0644)
 			Expect(err).NotTo(HaveOccurred())
 
-			globalJson, err := dotnetcoresdk.FindGlobalJson(subDirs)
+			globalJson, err := dotnetcoresdk.FindGlobalJson(subDirs, "")
 			Expect(err).NotTo(HaveOccurred())
 			Expect(globalJson).NotTo(BeNil())
 			Expect(globalJson.Sdk).NotTo(BeNil())
 			Expect(*globalJson.Sdk.Version).To(Equal("7.0.200"))
 		})
 
+		it("does not search above the root directory", func() {
+			tempDir := t.TempDir()
+			rootDir := filepath.Join(tempDir, "workspace")
+			subDir := filepath.Join(rootDir, "src")
+			Expect(os.MkdirAll(subDir, 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(tempDir, "global.json"), []byte(`{"sdk": {"version": "7.0.200"}}`), 0644)).To(Succeed())
+
+			globalJson, err := dotnetcoresdk.FindGlobalJson(subDir, rootDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(globalJson).To(BeNil())
+
+			Expect(os.WriteFile(filepath.Join(rootDir, "global.json"), []byte(`{"sdk": {"version": "8.0.100"}}`), 0644)).To(Succeed())
+
+			globalJson, err = dotnetcoresdk.FindGlobalJson(subDir, rootDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(*globalJson.Sdk.Version).To(Equal("8.0.100"))
+		})
+
 		it("returns nil if no global.json is found", func() {
 			tempDir := t.TempDir()
 
-			globalJson, err := dotnetcoresdk.FindGlobalJson(tempDir)
+			globalJson, err := dotnetcoresdk.FindGlobalJson(tempDir, "")
 			Expect(err).NotTo(HaveOccurred())
 			Expect(globalJson).To(BeNil())
 		})
@@ -89,20 +131,83 @@ func testGlobalFileParser(t *testing.T, context spec.G, it spec.S) {
 			}`), 0644)
 			Expect(err).NotTo(HaveOccurred())
 
-			globalJson, err := dotnetcoresdk.FindGlobalJson(subDirs)
+			globalJson, err := dotnetcoresdk.FindGlobalJson(subDirs, "")
 			Expect(err).NotTo(HaveOccurred())
 			Expect(globalJson).NotTo(BeNil())
 			Expect(globalJson.Sdk).NotTo(BeNil())
 			Expect(*globalJson.Sdk.Version).To(Equal("7.0.400"))
 		})
 
+		it("parses the msbuild-sdks", func() {
+			tempDir := t.TempDir()
+			err := os.WriteFile(filepath.Join(tempDir, "global.json"), []byte(`{
+				"sdk": {
+					"version": "8.0.100"
+				},
+				"msbuild-sdks": {
+					"Microsoft.Build.Traversal": "4.1.0",
+					"MSBuild.Sdk.Extras": "3.0.44"
+				}
+			}`), 0644)
+			Expect(err).NotTo(HaveOccurred())
+
+			globalJson, err := dotnetcoresdk.FindGlobalJson(tempDir, "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(globalJson.MsbuildSdks).To(Equal(map[string]string{
+				"Microsoft.Build.Traversal": "4.1.0",
+				"MSBuild.Sdk.Extras":        "3.0.44",
+			}))
+		})
+
+		it("accepts comments and trailing commas like the .NET host", func() {
+			tempDir := t.TempDir()
+			err := os.WriteFile(filepath.Join(tempDir, "global.json"), []byte(`{
+				// The SDK used in CI
+				"sdk": {
+					"version": "8.0.100", /* keep in sync with the Dockerfile */
+					"rollForward": "latestFeature",
+					"paths": ["// not a comment", "/* nor this */",],
+				},
+			}`), 0644)
+			Expect(err).NotTo(HaveOccurred())
+
+			globalJson, err := dotnetcoresdk.FindGlobalJson(tempDir, "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(*globalJson.Sdk.Version).To(Equal("8.0.100"))
+			Expect(*globalJson.Sdk.RollForward).To(Equal("latestFeature"))
+			Expect(globalJson.Sdk.Paths).To(Equal([]string{"// not a comment", "/* nor this */"}))
+		})
+
+		it("reports the line and column of a syntax error", func() {
+			tempDir := t.TempDir()
+			err := os.WriteFile(filepath.Join(tempDir, "global.json"), []byte("{\n  \"sdk\": {\n    version: \"8.0.100\"\n  }\n}"), 0644)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = dotnetcoresdk.FindGlobalJson(tempDir, "")
+			Expect(err).To(MatchError(ContainSubstring("failed to parse global.json: line 3, column 5: invalid character 'v'")))
+
+			var syntaxError dotnetcoresdk.JSONSyntaxError
+			Expect(errors.As(err, &syntaxError)).To(BeTrue())
+			Expect(syntaxError.Line).To(Equal(3))
+			Expect(syntaxError.Column).To(Equal(5))
+		})
+
+		it("reports the line and column of an unterminated comment", func() {
+			tempDir := t.TempDir()
+			err := os.WriteFile(filepath.Join(tempDir, "global.json"), []byte("{\n  /* the sdk\n}"), 0644)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = dotnetcoresdk.FindGlobalJson(tempDir, "")
+			Expect(err).To(MatchError("failed to parse global.json: line 2, column 3: unterminated comment"))
+		})
+
 		it("returns an error for invalid global.json", func() {
 			tempDir := t.TempDir()
 			globalJsonPath := filepath.Join(tempDir, "global.json")
 			err := os.WriteFile(globalJsonPath, []byte(`{ invalid json }`), 0644)
 			Expect(err).NotTo(HaveOccurred())
 
-			_, err = dotnetcoresdk.FindGlobalJson(tempDir)
+			_, err = dotnetcoresdk.FindGlobalJson(tempDir, "")
 			Expect(err).To(MatchError(ContainSubstring("failed to parse global.json")))
 		})
 	})
@@ -143,6 +248,29 @@ func testGlobalFileParser(t *testing.T, context spec.G, it spec.S) {
 			}))
 		})
 
+		it("compares the rollForward value case-insensitively", func() {
+			constraints, err := dotnetcoresdk.GetRollforwardConstraints("7.0.150", "LATESTFEATURE")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(constraints).To(Equal([]string{
+				">= 7.0.150, 7.0.*",
+			}))
+		})
+
+		it("returns an error for an unknown rollForward value", func() {
+			_, err := dotnetcoresdk.GetRollforwardConstraints("7.0.150", "lastestMinor")
+			Expect(err).To(MatchError("invalid rollForward value 'lastestMinor' in global.json: must be one of [patch, feature, minor, major, latestPatch, latestFeature, latestMinor, latestMajor, disabled]"))
+		})
+
+		it("returns an error for a malformed version", func() {
+			_, err := dotnetcoresdk.GetRollforwardConstraints("not-a-version", "patch")
+			Expect(err).To(MatchError(ContainSubstring("invalid version 'not-a-version' for sdk.version in global.json")))
+
+			var versionErr dotnetcoresdk.InvalidVersionError
+			Expect(errors.As(err, &versionErr)).To(BeTrue())
+			Expect(versionErr.File).To(Equal("global.json"))
+			Expect(versionErr.Field).To(Equal("sdk.version"))
+		})
+
 		it("generates constraints for latest major rollForward", func() {
 			constraints, err := dotnetcoresdk.GetRollforwardConstraints("9.2.400", "latestMajor")
 			Expect(err).NotTo(HaveOccurred())
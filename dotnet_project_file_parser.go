@@ -0,0 +1,191 @@
+package dotnetcoresdk
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	targetFrameworkPattern   = regexp.MustCompile(`^net(?:coreapp)?(\d+)\.(\d+)(?:-.+)?$`)
+	propertyReferencePattern = regexp.MustCompile(`\$\(([A-Za-z_][A-Za-z0-9_.-]*)\)`)
+)
+
+// DirectoryBuildProps is the MSBuild file that is imported by every project in
+// the directory containing it and in its subdirectories.
+const DirectoryBuildProps = "Directory.Build.props"
+
+type ProjectFile struct {
+	PropertyGroups []PropertyGroup `xml:"PropertyGroup"`
+
+	// Inherited holds the properties defined before the project is evaluated,
+	// i.e. those from the imported Directory.Build.props
+	Inherited MSBuildProperties `xml:"-"`
+}
+
+type PropertyGroup struct {
+	Properties []struct {
+		XMLName xml.Name
+		Value   string `xml:",chardata"`
+	} `xml:",any"`
+}
+
+// MSBuildProperties maps MSBuild property names to their evaluated values.
+// Like in MSBuild, property names are case-insensitive.
+type MSBuildProperties map[string]string
+
+// Get returns the value of the named property, or an empty string if it is not
+// defined.
+func (p MSBuildProperties) Get(name string) string {
+	return p[strings.ToLower(name)]
+}
+
+// Expand replaces $(Property) references in the value with the values of the
+// properties. Undefined properties expand to an empty string, and property
+// functions are left as they are.
+func (p MSBuildProperties) Expand(value string) string {
+	return propertyReferencePattern.ReplaceAllStringFunc(value, func(reference string) string {
+		return p.Get(propertyReferencePattern.FindStringSubmatch(reference)[1])
+	})
+}
+
+// Properties evaluates the properties of the project file in document order,
+// starting from the inherited properties. This is a small subset of the MSBuild
+// evaluation: conditions and imports other than Directory.Build.props are not
+// evaluated.
+func (p ProjectFile) Properties() MSBuildProperties {
+	properties := MSBuildProperties{}
+	for name, value := range p.Inherited {
+		properties[name] = value
+	}
+
+	for _, group := range p.PropertyGroups {
+		for _, property := range group.Properties {
+			properties[strings.ToLower(property.XMLName.Local)] = properties.Expand(strings.TrimSpace(property.Value))
+		}
+	}
+
+	return properties
+}
+
+// TargetFrameworks returns every target framework moniker declared in the
+// project file through either TargetFramework or TargetFrameworks.
+func (p ProjectFile) TargetFrameworks() []string {
+	properties := p.Properties()
+
+	var frameworks []string
+	for _, value := range []string{properties.Get("TargetFramework"), properties.Get("TargetFrameworks")} {
+		for _, framework := range strings.Split(value, ";") {
+			framework = strings.TrimSpace(framework)
+			if framework != "" {
+				frameworks = append(frameworks, framework)
+			}
+		}
+	}
+
+	return frameworks
+}
+
+// FindProjectFiles returns the paths of all .csproj, .fsproj and .vbproj
+// files in the given directory, sorted by name.
+func FindProjectFiles(dir string) ([]string, error) {
+	var projectFiles []string
+	for _, pattern := range []string{"*.csproj", "*.fsproj", "*.vbproj"} {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		projectFiles = append(projectFiles, matches...)
+	}
+
+	sort.Strings(projectFiles)
+
+	return projectFiles, nil
+}
+
+// ParseProjectFile parses the project file at the given path, inheriting the
+// properties of the nearest Directory.Build.props at or below the root
+// directory.
+func ParseProjectFile(path, root string) (ProjectFile, error) {
+	projectFile, err := parseMSBuildFile(path)
+	if err != nil {
+		return ProjectFile{}, err
+	}
+
+	propsPath, err := FindDirectoryBuildProps(filepath.Dir(path), root)
+	if err != nil {
+		return ProjectFile{}, err
+	}
+
+	if propsPath != "" {
+		props, err := parseMSBuildFile(propsPath)
+		if err != nil {
+			return ProjectFile{}, err
+		}
+		projectFile.Inherited = props.Properties()
+	}
+
+	return projectFile, nil
+}
+
+// ParseDirectoryBuildProps parses the Directory.Build.props file at the given
+// path. Like MSBuild, it does not import the Directory.Build.props files of
+// parent directories.
+func ParseDirectoryBuildProps(path string) (ProjectFile, error) {
+	return parseMSBuildFile(path)
+}
+
+// FindDirectoryBuildProps returns the path of the Directory.Build.props file
+// in the given directory or its parents, up to the root directory (see
+// findUp). It returns an empty string if there is none.
+func FindDirectoryBuildProps(dir, root string) (string, error) {
+	return findUp(dir, root, DirectoryBuildProps)
+}
+
+func parseMSBuildFile(path string) (ProjectFile, error) {
+	fileContents, err := os.ReadFile(path)
+	if err != nil {
+		return ProjectFile{}, fmt.Errorf("failed to read %s: %w", filepath.Base(path), err)
+	}
+
+	var projectFile ProjectFile
+	err = xml.Unmarshal(fileContents, &projectFile)
+	if err != nil {
+		return ProjectFile{}, fmt.Errorf("failed to parse %s: %w", filepath.Base(path), err)
+	}
+
+	return projectFile, nil
+}
+
+// GetSdkConstraintFromTargetFrameworks returns a version constraint for the
+// SDK able to build the highest of the given target frameworks (e.g. net8.0
+// yields 8.0.*). Frameworks that are not built by the .NET SDK, such as
+// netstandard2.0 or net48, are ignored. If none of the frameworks are
+// supported, ok is false.
+func GetSdkConstraintFromTargetFrameworks(frameworks []string) (constraint string, ok bool) {
+	var major, minor int
+	for _, framework := range frameworks {
+		matches := targetFrameworkPattern.FindStringSubmatch(strings.ToLower(framework))
+		if matches == nil {
+			continue
+		}
+
+		frameworkMajor, _ := strconv.Atoi(matches[1])
+		frameworkMinor, _ := strconv.Atoi(matches[2])
+		if frameworkMajor > major || (frameworkMajor == major && frameworkMinor > minor) {
+			major, minor = frameworkMajor, frameworkMinor
+			ok = true
+		}
+	}
+
+	if !ok {
+		return "", false
+	}
+
+	return fmt.Sprintf("%d.%d.*", major, minor), true
+}
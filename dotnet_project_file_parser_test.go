@@ -0,0 +1,141 @@
+package dotnetcoresdk_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	dotnetcoresdk "github.com/paketo-buildpacks/dotnet-core-sdk"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testProjectFileParser(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+	)
+
+	context("FindProjectFiles", func() {
+		it("finds C#, F# and VB project files in the directory", func() {
+			tempDir := t.TempDir()
+			for _, name := range []string{"b.fsproj", "a.csproj", "c.vbproj", "README.md"} {
+				Expect(os.WriteFile(filepath.Join(tempDir, name), []byte(""), 0644)).To(Succeed())
+			}
+
+			projectFiles, err := dotnetcoresdk.FindProjectFiles(tempDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(projectFiles).To(Equal([]string{
+				filepath.Join(tempDir, "a.csproj"),
+				filepath.Join(tempDir, "b.fsproj"),
+				filepath.Join(tempDir, "c.vbproj"),
+			}))
+		})
+
+		it("returns nothing if there are no project files", func() {
+			projectFiles, err := dotnetcoresdk.FindProjectFiles(t.TempDir())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(projectFiles).To(BeEmpty())
+		})
+	})
+
+	context("ParseProjectFile", func() {
+		it("parses the target frameworks from every property group", func() {
+			projectFilePath := filepath.Join(t.TempDir(), "app.csproj")
+			Expect(os.WriteFile(projectFilePath, []byte(`<Project Sdk="Microsoft.NET.Sdk">
+				<PropertyGroup>
+					<OutputType>Exe</OutputType>
+					<TargetFramework>net8.0</TargetFramework>
+				</PropertyGroup>
+				<PropertyGroup>
+					<TargetFrameworks> net9.0 ; net10.0-windows </TargetFrameworks>
+				</PropertyGroup>
+			</Project>`), 0644)).To(Succeed())
+
+			projectFile, err := dotnetcoresdk.ParseProjectFile(projectFilePath, filepath.Dir(projectFilePath))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(projectFile.TargetFrameworks()).To(Equal([]string{"net8.0", "net9.0", "net10.0-windows"}))
+		})
+
+		it("evaluates property references and inherits the properties of Directory.Build.props", func() {
+			rootDir := t.TempDir()
+			Expect(os.WriteFile(filepath.Join(rootDir, "Directory.Build.props"), []byte(`<Project>
+				<PropertyGroup>
+					<LatestTfm>net9.0</LatestTfm>
+					<UseMaui>false</UseMaui>
+				</PropertyGroup>
+			</Project>`), 0644)).To(Succeed())
+
+			Expect(os.MkdirAll(filepath.Join(rootDir, "src", "app"), os.ModePerm)).To(Succeed())
+			projectFilePath := filepath.Join(rootDir, "src", "app", "app.csproj")
+			Expect(os.WriteFile(projectFilePath, []byte(`<Project Sdk="Microsoft.NET.Sdk">
+				<PropertyGroup>
+					<TargetFrameworks>net8.0;$(latesttfm);$(Undefined)</TargetFrameworks>
+					<usemaui>true</usemaui>
+				</PropertyGroup>
+			</Project>`), 0644)).To(Succeed())
+
+			projectFile, err := dotnetcoresdk.ParseProjectFile(projectFilePath, rootDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(projectFile.TargetFrameworks()).To(Equal([]string{"net8.0", "net9.0"}))
+			Expect(projectFile.Properties().Get("UseMaui")).To(Equal("true"))
+		})
+
+		it("does not inherit a Directory.Build.props above the root directory", func() {
+			parentDir := t.TempDir()
+			Expect(os.WriteFile(filepath.Join(parentDir, "Directory.Build.props"), []byte(`<Project>
+				<PropertyGroup>
+					<TargetFramework>net6.0</TargetFramework>
+				</PropertyGroup>
+			</Project>`), 0644)).To(Succeed())
+
+			rootDir := filepath.Join(parentDir, "app")
+			Expect(os.MkdirAll(rootDir, os.ModePerm)).To(Succeed())
+			projectFilePath := filepath.Join(rootDir, "app.csproj")
+			Expect(os.WriteFile(projectFilePath, []byte(`<Project Sdk="Microsoft.NET.Sdk"></Project>`), 0644)).To(Succeed())
+
+			projectFile, err := dotnetcoresdk.ParseProjectFile(projectFilePath, rootDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(projectFile.TargetFrameworks()).To(BeEmpty())
+		})
+
+		it("returns an error for an invalid project file", func() {
+			projectFilePath := filepath.Join(t.TempDir(), "app.csproj")
+			Expect(os.WriteFile(projectFilePath, []byte(`<Project>`), 0644)).To(Succeed())
+
+			_, err := dotnetcoresdk.ParseProjectFile(projectFilePath, filepath.Dir(projectFilePath))
+			Expect(err).To(MatchError(ContainSubstring("failed to parse app.csproj")))
+		})
+	})
+
+	context("FindDirectoryBuildProps", func() {
+		it("walks up the tree to the nearest Directory.Build.props", func() {
+			rootDir := t.TempDir()
+			Expect(os.WriteFile(filepath.Join(rootDir, "Directory.Build.props"), []byte(`<Project />`), 0644)).To(Succeed())
+			Expect(os.MkdirAll(filepath.Join(rootDir, "src", "app"), os.ModePerm)).To(Succeed())
+
+			path, err := dotnetcoresdk.FindDirectoryBuildProps(filepath.Join(rootDir, "src", "app"), "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(path).To(Equal(filepath.Join(rootDir, "Directory.Build.props")))
+		})
+	})
+
+	context("GetSdkConstraintFromTargetFrameworks", func() {
+		it("returns a constraint for the highest target framework", func() {
+			constraint, ok := dotnetcoresdk.GetSdkConstraintFromTargetFrameworks([]string{"net8.0", "net10.0-android", "net9.0"})
+			Expect(ok).To(BeTrue())
+			Expect(constraint).To(Equal("10.0.*"))
+		})
+
+		it("supports netcoreapp target frameworks", func() {
+			constraint, ok := dotnetcoresdk.GetSdkConstraintFromTargetFrameworks([]string{"netcoreapp3.1"})
+			Expect(ok).To(BeTrue())
+			Expect(constraint).To(Equal("3.1.*"))
+		})
+
+		it("ignores target frameworks that are not built by the .NET SDK", func() {
+			_, ok := dotnetcoresdk.GetSdkConstraintFromTargetFrameworks([]string{"netstandard2.0", "net48"})
+			Expect(ok).To(BeFalse())
+		})
+	})
+}
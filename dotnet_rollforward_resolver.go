@@ -1,67 +1,179 @@
 package dotnetcoresdk
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"runtime"
 	"sort"
 	"strings"
 
 	"github.com/BurntSushi/toml"
-	"github.com/Masterminds/semver"
+	"github.com/Masterminds/semver/v3"
 	"github.com/paketo-buildpacks/packit/v2/postal"
 )
 
-func ResolveWithRollforward(path string, version string, rollForward string, stack string) (postal.Dependency, error) {
+// ResolveWithRollforward selects the highest SDK from buildpack.toml that
+// satisfies the first matching global.json roll-forward constraint. Following
+// the .NET host, prerelease SDKs are only considered when allowPrerelease is
+// set or when the requested version is itself a prerelease.
+func ResolveWithRollforward(path string, version string, rollForward string, allowPrerelease bool, stack string) (postal.Dependency, error) {
+	dependency, _, err := resolveWithRollforward(path, version, rollForward, allowPrerelease, stack)
+	return dependency, err
+}
+
+// resolveWithRollforward behaves like ResolveWithRollforward and additionally
+// returns the roll-forward constraints that were tried, in order.
+func resolveWithRollforward(path string, version string, rollForward string, allowPrerelease bool, stack string) (postal.Dependency, []string, error) {
 	sdkDependencies, supportedVersions, err := filterBuildpackTOML(path, DotnetDependency, stack)
 	if err != nil {
-		return postal.Dependency{}, err
+		return postal.Dependency{}, nil, err
+	}
+
+	depVersions := make([]*semver.Version, len(sdkDependencies))
+	for i, dependency := range sdkDependencies {
+		depVersions[i], err = semver.NewVersion(dependency.Version)
+		if err != nil {
+			return postal.Dependency{}, nil, InvalidVersionError{File: "buildpack.toml", Field: "metadata.dependencies.version", Version: dependency.Version, Err: err}
+		}
+	}
+
+	index, tried, err := selectWithRollforward(depVersions, version, rollForward, allowPrerelease)
+	if err != nil {
+		return postal.Dependency{}, nil, err
 	}
 
+	if index < 0 {
+		return postal.Dependency{}, tried, fmt.Errorf("failed to resolve version %s with roll-forward policy '%s'. Supported versions are: [%s]",
+			version,
+			rollForward,
+			strings.Join(supportedVersions, ", "),
+		)
+	}
+
+	return sdkDependencies[index], tried, nil
+}
+
+type LocalSdk struct {
+	Root    string
+	Version string
+}
+
+// FindLocalSdk searches the global.json sdk.paths in order for an SDK
+// installation that satisfies the requested version and roll-forward policy.
+// The search stops at the $host$ entry, since SDKs installed alongside the
+// host are provided by this buildpack. It returns nil if no installation
+// matches.
+func FindLocalSdk(paths []string, version string, rollForward string, allowPrerelease bool) (*LocalSdk, error) {
+	for _, root := range paths {
+		if root == HostSdkPath {
+			return nil, nil
+		}
+
+		entries, err := os.ReadDir(filepath.Join(root, "sdk"))
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+
+		var sdkVersions []*semver.Version
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				continue
+			}
+
+			// Directories that are not SDK versions are ignored, as the host does
+			sdkVersion, err := semver.StrictNewVersion(entry.Name())
+			if err != nil {
+				continue
+			}
+			sdkVersions = append(sdkVersions, sdkVersion)
+		}
+
+		index, _, err := selectWithRollforward(sdkVersions, version, rollForward, allowPrerelease)
+		if err != nil {
+			return nil, err
+		}
+
+		if index >= 0 {
+			return &LocalSdk{Root: root, Version: sdkVersions[index].Original()}, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// withinDir reports whether path is dir or one of its descendants, once any
+// symlinks in either have been resolved.
+func withinDir(path, dir string) bool {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = resolved
+	}
+
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// selectWithRollforward returns the index of the highest of the given versions
+// that satisfies the first matching roll-forward constraint, or -1 if none do,
+// along with the constraints that were tried in order.
+func selectWithRollforward(versions []*semver.Version, version string, rollForward string, allowPrerelease bool) (int, []string, error) {
 	constraints, err := GetRollforwardConstraints(version, rollForward)
 	if err != nil {
-		return postal.Dependency{}, err
+		return -1, nil, err
 	}
 
+	requestedVersion, err := semver.StrictNewVersion(version)
+	if err != nil {
+		return -1, nil, InvalidVersionError{File: "global.json", Field: "sdk.version", Version: version, Err: err}
+	}
+	includePrerelease := allowPrerelease || requestedVersion.Prerelease() != ""
+
 	// Iterate through each rollforward contstraint to find compatible dependencies
 	// The first constraint to match is used even if later constraints would match a newer version
-	compatibleVersions := []postal.Dependency{}
+	var tried []string
+	compatibleIndices := []int{}
 	for _, constraint := range constraints {
-		for _, dependency := range sdkDependencies {
-			depVersion := semver.MustParse(dependency.Version)
-			constraintVersion, err := semver.NewConstraint(constraint)
-			if err != nil {
-				return postal.Dependency{}, err
-			}
+		tried = append(tried, constraint)
 
-			if constraintVersion.Check(depVersion) {
-				compatibleVersions = append(compatibleVersions, dependency)
+		constraintVersion, err := semver.NewConstraint(constraint)
+		if err != nil {
+			return -1, tried, err
+		}
+		constraintVersion.IncludePrerelease = includePrerelease
+
+		for i := range versions {
+			if constraintVersion.Check(versions[i]) {
+				compatibleIndices = append(compatibleIndices, i)
 			}
 		}
 
 		// Stop once a constraint has matched at least one dependency
-		if len(compatibleVersions) > 0 {
+		if len(compatibleIndices) > 0 {
 			break
 		}
 	}
 
-	if len(compatibleVersions) == 0 {
-		return postal.Dependency{}, fmt.Errorf("failed to resolve version %s with roll-forward policy '%s'. Supported versions are: [%s]",
-			version,
-			rollForward,
-			strings.Join(supportedVersions, ", "),
-		)
+	if len(compatibleIndices) == 0 {
+		return -1, tried, nil
 	}
 
 	// return the highest compatible version
-	sort.Slice(compatibleVersions, func(i, j int) bool {
-		iVersion := semver.MustParse(compatibleVersions[i].Version)
-		jVersion := semver.MustParse(compatibleVersions[j].Version)
-		return iVersion.GreaterThan(jVersion)
+	sort.Slice(compatibleIndices, func(i, j int) bool {
+		return versions[compatibleIndices[i]].GreaterThan(versions[compatibleIndices[j]])
 	})
 
-	return compatibleVersions[0], nil
-
+	return compatibleIndices[0], tried, nil
 }
 
 func filterBuildpackTOML(path, dependencyID, stack string) ([]postal.Dependency, []string, error) {
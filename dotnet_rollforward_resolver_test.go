@@ -1,6 +1,7 @@
 package dotnetcoresdk_test
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
@@ -53,6 +54,11 @@ func testRollforwardResolver(t *testing.T, context spec.G, it spec.S) {
 				id = "dotnet-sdk"
 				stacks = ["some-stack"]
 				version = "10.0.100"
+
+			[[metadata.dependencies]]
+				id = "dotnet-sdk"
+				stacks = ["some-stack"]
+				version = "10.0.200-rc.1.25451.107"
 		`), 0644)
 		Expect(err).NotTo(HaveOccurred())
 	})
@@ -63,21 +69,123 @@ func testRollforwardResolver(t *testing.T, context spec.G, it spec.S) {
 				filepath.Join(cnbDir, "buildpack.toml"),
 				"9.0.200",
 				"feature",
+				false,
 				"some-stack",
 			)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(dep.Version).To(Equal("9.0.366"))
 		})
 
+		context("when the dependencies include a prerelease SDK", func() {
+			it("ignores prerelease versions by default", func() {
+				dep, err := dotnetcoresdk.ResolveWithRollforward(
+					filepath.Join(cnbDir, "buildpack.toml"),
+					"10.0.100",
+					"latestFeature",
+					false,
+					"some-stack",
+				)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(dep.Version).To(Equal("10.0.100"))
+			})
+
+			it("includes prerelease versions when allowPrerelease is set", func() {
+				dep, err := dotnetcoresdk.ResolveWithRollforward(
+					filepath.Join(cnbDir, "buildpack.toml"),
+					"10.0.100",
+					"latestFeature",
+					true,
+					"some-stack",
+				)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(dep.Version).To(Equal("10.0.200-rc.1.25451.107"))
+			})
+
+			it("includes prerelease versions when the requested version is a prerelease", func() {
+				dep, err := dotnetcoresdk.ResolveWithRollforward(
+					filepath.Join(cnbDir, "buildpack.toml"),
+					"10.0.200-rc.1.25451.107",
+					"patch",
+					false,
+					"some-stack",
+				)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(dep.Version).To(Equal("10.0.200-rc.1.25451.107"))
+			})
+		})
+
+		it("returns an error when the requested version is malformed", func() {
+			_, err := dotnetcoresdk.ResolveWithRollforward(
+				filepath.Join(cnbDir, "buildpack.toml"),
+				"8.0",
+				"patch",
+				false,
+				"some-stack",
+			)
+			Expect(err).To(MatchError(ContainSubstring("invalid version '8.0' for sdk.version in global.json")))
+		})
+
+		it("returns an error when a buildpack.toml dependency version is malformed", func() {
+			err := os.WriteFile(filepath.Join(cnbDir, "buildpack.toml"), []byte(`api = "0.2"
+			[[metadata.dependencies]]
+				id = "dotnet-sdk"
+				stacks = ["some-stack"]
+				version = "not-a-version"
+			`), 0644)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = dotnetcoresdk.ResolveWithRollforward(
+				filepath.Join(cnbDir, "buildpack.toml"),
+				"8.0.100",
+				"patch",
+				false,
+				"some-stack",
+			)
+			Expect(err).To(MatchError(ContainSubstring("invalid version 'not-a-version' for metadata.dependencies.version in buildpack.toml")))
+
+			var versionErr dotnetcoresdk.InvalidVersionError
+			Expect(errors.As(err, &versionErr)).To(BeTrue())
+			Expect(versionErr.File).To(Equal("buildpack.toml"))
+		})
+
 		it("returns an error when no compatible version is found", func() {
 			_, err := dotnetcoresdk.ResolveWithRollforward(
 				filepath.Join(cnbDir, "buildpack.toml"),
 				"8.0.100",
 				"patch",
+				false,
 				"some-stack",
 			)
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("failed to resolve version 8.0.100 with roll-forward policy 'patch'"))
 		})
 	})
+	context("FindLocalSdk", func() {
+		var sdkRoot string
+
+		it.Before(func() {
+			sdkRoot = t.TempDir()
+			for _, version := range []string{"9.0.200", "9.0.203", "10.0.100", "not-a-version"} {
+				Expect(os.MkdirAll(filepath.Join(sdkRoot, "sdk", version), os.ModePerm)).To(Succeed())
+			}
+		})
+
+		it("finds an SDK in the given paths matching the roll-forward policy", func() {
+			localSdk, err := dotnetcoresdk.FindLocalSdk([]string{filepath.Join(t.TempDir(), "missing"), sdkRoot}, "9.0.201", "latestPatch", false)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(localSdk).To(Equal(&dotnetcoresdk.LocalSdk{Root: sdkRoot, Version: "9.0.203"}))
+		})
+
+		it("returns nil when no SDK matches", func() {
+			localSdk, err := dotnetcoresdk.FindLocalSdk([]string{sdkRoot}, "8.0.100", "patch", false)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(localSdk).To(BeNil())
+		})
+
+		it("stops searching at the $host$ path", func() {
+			localSdk, err := dotnetcoresdk.FindLocalSdk([]string{"$host$", sdkRoot}, "9.0.200", "patch", false)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(localSdk).To(BeNil())
+		})
+	})
 }
@@ -0,0 +1,88 @@
+package dotnetcoresdk
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+type RuntimeConfig struct {
+	RuntimeOptions struct {
+		Framework  *RuntimeConfigFramework  `json:"framework,omitempty"`
+		Frameworks []RuntimeConfigFramework `json:"frameworks,omitempty"`
+	} `json:"runtimeOptions"`
+}
+
+type RuntimeConfigFramework struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// FindRuntimeConfig returns the path of the first *.runtimeconfig.json file
+// in the given directory, or an empty string if there is none.
+func FindRuntimeConfig(dir string) (string, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, "*.runtimeconfig.json"))
+	if err != nil {
+		return "", err
+	}
+
+	if len(matches) == 0 {
+		return "", nil
+	}
+
+	sort.Strings(matches)
+
+	return matches[0], nil
+}
+
+func ParseRuntimeConfig(path string) (RuntimeConfig, error) {
+	fileContents, err := os.ReadFile(path)
+	if err != nil {
+		return RuntimeConfig{}, fmt.Errorf("failed to read %s: %w", filepath.Base(path), err)
+	}
+
+	var runtimeConfig RuntimeConfig
+	err = json.Unmarshal(fileContents, &runtimeConfig)
+	if err != nil {
+		return RuntimeConfig{}, fmt.Errorf("failed to parse %s: %w", filepath.Base(path), err)
+	}
+
+	return runtimeConfig, nil
+}
+
+// GetSdkConstraint returns a version constraint for the SDK whose bundled
+// runtime matches the major.minor of the highest framework referenced by the
+// runtimeconfig.json (e.g. 8.0.11 yields 8.0.*). If the runtimeconfig.json
+// references no frameworks, ok is false.
+func (r RuntimeConfig) GetSdkConstraint() (constraint string, ok bool, err error) {
+	frameworks := r.RuntimeOptions.Frameworks
+	if r.RuntimeOptions.Framework != nil {
+		frameworks = append(frameworks, *r.RuntimeOptions.Framework)
+	}
+
+	var highest *semver.Version
+	for _, framework := range frameworks {
+		if framework.Version == "" {
+			continue
+		}
+
+		version, err := semver.NewVersion(framework.Version)
+		if err != nil {
+			return "", false, fmt.Errorf("failed to parse version of framework %s in runtimeconfig.json: %w", framework.Name, err)
+		}
+
+		if highest == nil || version.GreaterThan(highest) {
+			highest = version
+		}
+	}
+
+	if highest == nil {
+		return "", false, nil
+	}
+
+	return fmt.Sprintf("%d.%d.*", highest.Major(), highest.Minor()), true, nil
+}
@@ -0,0 +1,105 @@
+package dotnetcoresdk_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	dotnetcoresdk "github.com/paketo-buildpacks/dotnet-core-sdk"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testRuntimeConfigParser(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+	)
+
+	context("FindRuntimeConfig", func() {
+		it("finds the runtimeconfig.json in the directory", func() {
+			tempDir := t.TempDir()
+			Expect(os.WriteFile(filepath.Join(tempDir, "app.runtimeconfig.json"), []byte(`{}`), 0644)).To(Succeed())
+
+			path, err := dotnetcoresdk.FindRuntimeConfig(tempDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(path).To(Equal(filepath.Join(tempDir, "app.runtimeconfig.json")))
+		})
+
+		it("returns an empty path if there is no runtimeconfig.json", func() {
+			path, err := dotnetcoresdk.FindRuntimeConfig(t.TempDir())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(path).To(BeEmpty())
+		})
+	})
+
+	context("ParseRuntimeConfig", func() {
+		it("returns an error for an invalid runtimeconfig.json", func() {
+			path := filepath.Join(t.TempDir(), "app.runtimeconfig.json")
+			Expect(os.WriteFile(path, []byte(`{ invalid json }`), 0644)).To(Succeed())
+
+			_, err := dotnetcoresdk.ParseRuntimeConfig(path)
+			Expect(err).To(MatchError(ContainSubstring("failed to parse app.runtimeconfig.json")))
+		})
+	})
+
+	context("GetSdkConstraint", func() {
+		it("returns a constraint for a single framework", func() {
+			path := filepath.Join(t.TempDir(), "app.runtimeconfig.json")
+			Expect(os.WriteFile(path, []byte(`{
+				"runtimeOptions": {
+					"tfm": "net8.0",
+					"framework": {
+						"name": "Microsoft.NETCore.App",
+						"version": "8.0.11"
+					}
+				}
+			}`), 0644)).To(Succeed())
+
+			runtimeConfig, err := dotnetcoresdk.ParseRuntimeConfig(path)
+			Expect(err).NotTo(HaveOccurred())
+
+			constraint, ok, err := runtimeConfig.GetSdkConstraint()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeTrue())
+			Expect(constraint).To(Equal("8.0.*"))
+		})
+
+		it("returns a constraint for the highest of multiple frameworks", func() {
+			path := filepath.Join(t.TempDir(), "app.runtimeconfig.json")
+			Expect(os.WriteFile(path, []byte(`{
+				"runtimeOptions": {
+					"frameworks": [
+						{ "name": "Microsoft.NETCore.App", "version": "9.0.0" },
+						{ "name": "Microsoft.AspNetCore.App", "version": "9.0.1" }
+					]
+				}
+			}`), 0644)).To(Succeed())
+
+			runtimeConfig, err := dotnetcoresdk.ParseRuntimeConfig(path)
+			Expect(err).NotTo(HaveOccurred())
+
+			constraint, ok, err := runtimeConfig.GetSdkConstraint()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeTrue())
+			Expect(constraint).To(Equal("9.0.*"))
+		})
+
+		it("returns false when no frameworks are referenced", func() {
+			_, ok, err := dotnetcoresdk.RuntimeConfig{}.GetSdkConstraint()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeFalse())
+		})
+
+		it("returns an error when a framework version is invalid", func() {
+			runtimeConfig := dotnetcoresdk.RuntimeConfig{}
+			runtimeConfig.RuntimeOptions.Framework = &dotnetcoresdk.RuntimeConfigFramework{
+				Name:    "Microsoft.NETCore.App",
+				Version: "not-a-version",
+			}
+
+			_, _, err := runtimeConfig.GetSdkConstraint()
+			Expect(err).To(MatchError(ContainSubstring("failed to parse version of framework Microsoft.NETCore.App in runtimeconfig.json")))
+		})
+	})
+}
@@ -0,0 +1,59 @@
+package dotnetcoresdk
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+type ToolManifest struct {
+	Version int                     `json:"version"`
+	IsRoot  bool                    `json:"isRoot"`
+	Tools   map[string]ManifestTool `json:"tools"`
+
+	// Path is the location of the parsed manifest file
+	Path string `json:"-"`
+}
+
+type ManifestTool struct {
+	Version  string   `json:"version"`
+	Commands []string `json:"commands"`
+}
+
+// ToolIDs returns the IDs of the tools in the manifest, sorted by name.
+func (m ToolManifest) ToolIDs() []string {
+	var ids []string
+	for id := range m.Tools {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	return ids
+}
+
+// FindToolManifest looks for a local tool manifest, either as
+// .config/dotnet-tools.json or dotnet-tools.json, in the given directory and
+// its parents, up to the root directory (see findUp). It returns nil if there
+// is no manifest.
+func FindToolManifest(dir, root string) (*ToolManifest, error) {
+	filePath, err := findUp(dir, root, filepath.Join(".config", "dotnet-tools.json"), "dotnet-tools.json")
+	if err != nil || filePath == "" {
+		return nil, err
+	}
+
+	fileContents, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dotnet-tools.json: %w", err)
+	}
+
+	var manifest ToolManifest
+	err = json.Unmarshal(fileContents, &manifest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse dotnet-tools.json: %w", err)
+	}
+	manifest.Path = filePath
+
+	return &manifest, nil
+}
@@ -0,0 +1,116 @@
+package dotnetcoresdk_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	dotnetcoresdk "github.com/paketo-buildpacks/dotnet-core-sdk"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testToolManifest(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		workingDir string
+	)
+
+	it.Before(func() {
+		workingDir = t.TempDir()
+	})
+
+	context("FindToolManifest", func() {
+		context("when the manifest is in the .config directory", func() {
+			it.Before(func() {
+				Expect(os.MkdirAll(filepath.Join(workingDir, ".config"), os.ModePerm)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(workingDir, ".config", "dotnet-tools.json"), []byte(`{
+					"version": 1,
+					"isRoot": true,
+					"tools": {
+						"dotnet-ef": {
+							"version": "9.0.4",
+							"commands": ["dotnet-ef"]
+						},
+						"csharpier": {
+							"version": "0.30.6",
+							"commands": ["dotnet-csharpier"]
+						}
+					}
+				}`), 0644)).To(Succeed())
+			})
+
+			it("parses the manifest", func() {
+				manifest, err := dotnetcoresdk.FindToolManifest(workingDir, workingDir)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(manifest.Path).To(Equal(filepath.Join(workingDir, ".config", "dotnet-tools.json")))
+				Expect(manifest.IsRoot).To(BeTrue())
+				Expect(manifest.ToolIDs()).To(Equal([]string{"csharpier", "dotnet-ef"}))
+				Expect(manifest.Tools["dotnet-ef"]).To(Equal(dotnetcoresdk.ManifestTool{
+					Version:  "9.0.4",
+					Commands: []string{"dotnet-ef"},
+				}))
+			})
+		})
+
+		context("when the manifest is in a parent directory", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "dotnet-tools.json"), []byte(`{
+					"version": 1,
+					"tools": {
+						"dotnet-format": {"version": "5.1.250801", "commands": ["dotnet-format"]}
+					}
+				}`), 0644)).To(Succeed())
+				Expect(os.MkdirAll(filepath.Join(workingDir, "src", "app"), os.ModePerm)).To(Succeed())
+			})
+
+			it("walks up the tree to find it", func() {
+				manifest, err := dotnetcoresdk.FindToolManifest(filepath.Join(workingDir, "src", "app"), workingDir)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(manifest.Path).To(Equal(filepath.Join(workingDir, "dotnet-tools.json")))
+				Expect(manifest.ToolIDs()).To(Equal([]string{"dotnet-format"}))
+			})
+		})
+
+		context("when the manifest is above the root directory", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "dotnet-tools.json"), []byte(`{
+					"version": 1,
+					"tools": {
+						"dotnet-format": {"version": "5.1.250801", "commands": ["dotnet-format"]}
+					}
+				}`), 0644)).To(Succeed())
+				Expect(os.MkdirAll(filepath.Join(workingDir, "app"), os.ModePerm)).To(Succeed())
+			})
+
+			it("ignores it", func() {
+				manifest, err := dotnetcoresdk.FindToolManifest(filepath.Join(workingDir, "app"), filepath.Join(workingDir, "app"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(manifest).To(BeNil())
+			})
+		})
+
+		context("when there is no manifest", func() {
+			it("returns nil", func() {
+				manifest, err := dotnetcoresdk.FindToolManifest(workingDir, workingDir)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(manifest).To(BeNil())
+			})
+		})
+
+		context("failure cases", func() {
+			context("when the manifest is malformed", func() {
+				it.Before(func() {
+					Expect(os.WriteFile(filepath.Join(workingDir, "dotnet-tools.json"), []byte(`{`), 0644)).To(Succeed())
+				})
+
+				it("returns an error", func() {
+					_, err := dotnetcoresdk.FindToolManifest(workingDir, workingDir)
+					Expect(err).To(MatchError(ContainSubstring("failed to parse dotnet-tools.json")))
+				})
+			})
+		})
+	})
+}
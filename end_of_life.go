@@ -0,0 +1,76 @@
+package dotnetcoresdk
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/paketo-buildpacks/packit/v2/postal"
+	"github.com/paketo-buildpacks/packit/v2/scribe"
+)
+
+const defaultEOLWarningDays = 90
+
+type EndOfLifePolicy struct {
+	// WarningWindow is how long before its deprecation date an SDK is warned about
+	WarningWindow time.Duration
+
+	// FailOnEOL makes the build fail when an SDK is past its deprecation date
+	FailOnEOL bool
+}
+
+// LoadEndOfLifePolicy reads the end-of-life policy from the
+// BP_DOTNET_SDK_EOL_WARNING_DAYS and BP_DOTNET_SDK_FAIL_ON_EOL settings.
+func LoadEndOfLifePolicy() (EndOfLifePolicy, error) {
+	policy := EndOfLifePolicy{
+		WarningWindow: defaultEOLWarningDays * 24 * time.Hour,
+	}
+
+	if value, ok := os.LookupEnv(EOLWarningDays); ok {
+		days, err := strconv.Atoi(value)
+		if err != nil || days < 0 {
+			return EndOfLifePolicy{}, fmt.Errorf("invalid value '%s' for %s: must be a non-negative number of days", value, EOLWarningDays)
+		}
+		policy.WarningWindow = time.Duration(days) * 24 * time.Hour
+	}
+
+	if value, ok := os.LookupEnv(FailOnEOL); ok {
+		fail, err := strconv.ParseBool(value)
+		if err != nil {
+			return EndOfLifePolicy{}, fmt.Errorf("invalid value '%s' for %s: must be true or false", value, FailOnEOL)
+		}
+		policy.FailOnEOL = fail
+	}
+
+	return policy, nil
+}
+
+// Check warns when the dependency is within the warning window of its
+// deprecation date, and warns or fails when it is already past it.
+// Dependencies without a deprecation date are not checked.
+func (p EndOfLifePolicy) Check(dependency postal.Dependency, now time.Time, logger scribe.Emitter) error {
+	if dependency.DeprecationDate.IsZero() {
+		return nil
+	}
+
+	eolDate := dependency.DeprecationDate.Format("2006-01-02")
+
+	if !dependency.DeprecationDate.After(now) {
+		if p.FailOnEOL {
+			return fmt.Errorf("%s %s reached end of life on %s and %s is set: select a supported SDK version", dependency.Name, dependency.Version, eolDate, FailOnEOL)
+		}
+
+		logger.Subprocess(scribe.YellowColor(fmt.Sprintf("WARNING: %s %s reached end of life on %s and no longer receives security updates.", dependency.Name, dependency.Version, eolDate)))
+		logger.Break()
+		return nil
+	}
+
+	if dependency.DeprecationDate.Sub(now) <= p.WarningWindow {
+		days := int(dependency.DeprecationDate.Sub(now).Hours() / 24)
+		logger.Subprocess(scribe.YellowColor(fmt.Sprintf("WARNING: %s %s reaches end of life on %s (in %d days).", dependency.Name, dependency.Version, eolDate, days)))
+		logger.Break()
+	}
+
+	return nil
+}
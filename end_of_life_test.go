@@ -0,0 +1,116 @@
+package dotnetcoresdk_test
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"time"
+
+	dotnetcoresdk "github.com/paketo-buildpacks/dotnet-core-sdk"
+	"github.com/paketo-buildpacks/packit/v2/postal"
+	"github.com/paketo-buildpacks/packit/v2/scribe"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testEndOfLife(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		buffer *bytes.Buffer
+		logger scribe.Emitter
+		now    time.Time
+	)
+
+	it.Before(func() {
+		buffer = bytes.NewBuffer(nil)
+		logger = scribe.NewEmitter(buffer)
+		now = time.Date(2026, time.October, 1, 0, 0, 0, 0, time.UTC)
+	})
+
+	context("LoadEndOfLifePolicy", func() {
+		it.After(func() {
+			Expect(os.Unsetenv("BP_DOTNET_SDK_EOL_WARNING_DAYS")).To(Succeed())
+			Expect(os.Unsetenv("BP_DOTNET_SDK_FAIL_ON_EOL")).To(Succeed())
+		})
+
+		it("defaults to warning 90 days before end of life", func() {
+			policy, err := dotnetcoresdk.LoadEndOfLifePolicy()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(policy).To(Equal(dotnetcoresdk.EndOfLifePolicy{
+				WarningWindow: 90 * 24 * time.Hour,
+			}))
+		})
+
+		it("reads the policy from the environment", func() {
+			Expect(os.Setenv("BP_DOTNET_SDK_EOL_WARNING_DAYS", "14")).To(Succeed())
+			Expect(os.Setenv("BP_DOTNET_SDK_FAIL_ON_EOL", "true")).To(Succeed())
+
+			policy, err := dotnetcoresdk.LoadEndOfLifePolicy()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(policy).To(Equal(dotnetcoresdk.EndOfLifePolicy{
+				WarningWindow: 14 * 24 * time.Hour,
+				FailOnEOL:     true,
+			}))
+		})
+
+		it("returns an error for an invalid warning window", func() {
+			Expect(os.Setenv("BP_DOTNET_SDK_EOL_WARNING_DAYS", "soon")).To(Succeed())
+
+			_, err := dotnetcoresdk.LoadEndOfLifePolicy()
+			Expect(err).To(MatchError("invalid value 'soon' for BP_DOTNET_SDK_EOL_WARNING_DAYS: must be a non-negative number of days"))
+		})
+
+		it("returns an error for an invalid fail on EOL flag", func() {
+			Expect(os.Setenv("BP_DOTNET_SDK_FAIL_ON_EOL", "yes please")).To(Succeed())
+
+			_, err := dotnetcoresdk.LoadEndOfLifePolicy()
+			Expect(err).To(MatchError("invalid value 'yes please' for BP_DOTNET_SDK_FAIL_ON_EOL: must be true or false"))
+		})
+	})
+
+	context("Check", func() {
+		var dependency postal.Dependency
+
+		it.Before(func() {
+			dependency = postal.Dependency{
+				Name:    ".NET Core SDK",
+				Version: "8.0.424",
+			}
+		})
+
+		it("does nothing when the dependency has no deprecation date", func() {
+			Expect(dotnetcoresdk.EndOfLifePolicy{FailOnEOL: true}.Check(dependency, now, logger)).To(Succeed())
+			Expect(buffer.String()).To(BeEmpty())
+		})
+
+		it("does nothing when end of life is outside the warning window", func() {
+			dependency.DeprecationDate = now.Add(60 * 24 * time.Hour)
+
+			Expect(dotnetcoresdk.EndOfLifePolicy{WarningWindow: 30 * 24 * time.Hour}.Check(dependency, now, logger)).To(Succeed())
+			Expect(buffer.String()).To(BeEmpty())
+		})
+
+		it("warns when end of life is within the warning window", func() {
+			dependency.DeprecationDate = now.Add(20 * 24 * time.Hour)
+
+			Expect(dotnetcoresdk.EndOfLifePolicy{WarningWindow: 30 * 24 * time.Hour}.Check(dependency, now, logger)).To(Succeed())
+			Expect(buffer.String()).To(ContainSubstring("WARNING: .NET Core SDK 8.0.424 reaches end of life on 2026-10-21 (in 20 days)."))
+		})
+
+		it("warns when the dependency is past end of life", func() {
+			dependency.DeprecationDate = now.Add(-24 * time.Hour)
+
+			Expect(dotnetcoresdk.EndOfLifePolicy{}.Check(dependency, now, logger)).To(Succeed())
+			Expect(buffer.String()).To(ContainSubstring("WARNING: .NET Core SDK 8.0.424 reached end of life on 2026-09-30"))
+		})
+
+		it("fails when the dependency is past end of life and FailOnEOL is set", func() {
+			dependency.DeprecationDate = now.Add(-24 * time.Hour)
+
+			err := dotnetcoresdk.EndOfLifePolicy{FailOnEOL: true}.Check(dependency, now, logger)
+			Expect(err).To(MatchError(".NET Core SDK 8.0.424 reached end of life on 2026-09-30 and BP_DOTNET_SDK_FAIL_ON_EOL is set: select a supported SDK version"))
+		})
+	})
+}
@@ -0,0 +1,20 @@
+package dotnetcoresdk
+
+import "fmt"
+
+// InvalidVersionError is returned when a version read from a configuration
+// file is not a valid semantic version.
+type InvalidVersionError struct {
+	File    string
+	Field   string
+	Version string
+	Err     error
+}
+
+func (e InvalidVersionError) Error() string {
+	return fmt.Sprintf("invalid version '%s' for %s in %s: %s", e.Version, e.Field, e.File, e.Err)
+}
+
+func (e InvalidVersionError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,34 @@
+package fakes
+
+import "sync"
+
+type DotnetCLI struct {
+	ExecuteCall struct {
+		mutex     sync.Mutex
+		CallCount int
+		Receives  struct {
+			DotnetRoot string
+			WorkingDir string
+			Env        []string
+			Args       []string
+		}
+		Returns struct {
+			Error error
+		}
+		Stub func(string, string, []string, ...string) error
+	}
+}
+
+func (f *DotnetCLI) Execute(param1 string, param2 string, param3 []string, param4 ...string) error {
+	f.ExecuteCall.mutex.Lock()
+	defer f.ExecuteCall.mutex.Unlock()
+	f.ExecuteCall.CallCount++
+	f.ExecuteCall.Receives.DotnetRoot = param1
+	f.ExecuteCall.Receives.WorkingDir = param2
+	f.ExecuteCall.Receives.Env = param3
+	f.ExecuteCall.Receives.Args = param4
+	if f.ExecuteCall.Stub != nil {
+		return f.ExecuteCall.Stub(param1, param2, param3, param4...)
+	}
+	return f.ExecuteCall.Returns.Error
+}
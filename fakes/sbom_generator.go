@@ -8,6 +8,18 @@ import (
 )
 
 type SBOMGenerator struct {
+	GenerateCall struct {
+		mutex     sync.Mutex
+		CallCount int
+		Receives  struct {
+			Dir string
+		}
+		Returns struct {
+			SBOM  sbom.SBOM
+			Error error
+		}
+		Stub func(string) (sbom.SBOM, error)
+	}
 	GenerateFromDependencyCall struct {
 		mutex     sync.Mutex
 		CallCount int
@@ -23,6 +35,16 @@ type SBOMGenerator struct {
 	}
 }
 
+func (f *SBOMGenerator) Generate(param1 string) (sbom.SBOM, error) {
+	f.GenerateCall.mutex.Lock()
+	defer f.GenerateCall.mutex.Unlock()
+	f.GenerateCall.CallCount++
+	f.GenerateCall.Receives.Dir = param1
+	if f.GenerateCall.Stub != nil {
+		return f.GenerateCall.Stub(param1)
+	}
+	return f.GenerateCall.Returns.SBOM, f.GenerateCall.Returns.Error
+}
 func (f *SBOMGenerator) GenerateFromDependency(param1 postal.Dependency, param2 string) (sbom.SBOM, error) {
 	f.GenerateFromDependencyCall.mutex.Lock()
 	defer f.GenerateFromDependencyCall.mutex.Unlock()
@@ -0,0 +1,35 @@
+package dotnetcoresdk
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// findUp returns the path of the first of the given names found in dir or,
+// failing that, in the closest of its parents. The walk stops after the root
+// directory, or at the filesystem root when root is empty, so that files
+// outside the application cannot be picked up. It returns an empty string if
+// none of the names is found.
+func findUp(dir, root string, names ...string) (string, error) {
+	for {
+		for _, name := range names {
+			filePath := filepath.Join(dir, name)
+			_, err := os.Stat(filePath)
+			if err == nil {
+				return filePath, nil
+			}
+			if !errors.Is(err, os.ErrNotExist) && !errors.Is(err, syscall.ENOTDIR) {
+				return "", fmt.Errorf("failed to stat %s: %w", filePath, err)
+			}
+		}
+
+		parentDir := filepath.Dir(dir)
+		if dir == parentDir || (root != "" && filepath.Clean(dir) == filepath.Clean(root)) {
+			return "", nil
+		}
+		dir = parentDir
+	}
+}
@@ -11,7 +11,13 @@ func TestUnitDotnetCoreSDK(t *testing.T) {
 	suite := spec.New("dotnet-core-sdk", spec.Report(report.Terminal{}), spec.Sequential())
 	suite("Build", testBuild)
 	suite("Detect", testDetect)
+	suite("EndOfLife", testEndOfLife)
 	suite("GlobalFileParser", testGlobalFileParser)
+	suite("NugetCache", testNugetCache)
+	suite("ProjectFileParser", testProjectFileParser)
 	suite("RollforwardResolver", testRollforwardResolver)
+	suite("RuntimeConfigParser", testRuntimeConfigParser)
+	suite("ToolManifest", testToolManifest)
+	suite("Workloads", testWorkloads)
 	suite.Run(t)
 }
@@ -0,0 +1,105 @@
+package dotnetcoresdk
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/paketo-buildpacks/packit/v2"
+	"github.com/paketo-buildpacks/packit/v2/chronos"
+	"github.com/paketo-buildpacks/packit/v2/scribe"
+)
+
+// MsbuildSdksFromPlan returns the msbuild-sdks declared in global.json, as
+// passed through the build plan entries.
+func MsbuildSdksFromPlan(entries []packit.BuildpackPlanEntry) map[string]string {
+	for _, entry := range entries {
+		switch sdks := entry.Metadata["msbuild-sdks"].(type) {
+		case map[string]string:
+			return sdks
+		case map[string]interface{}:
+			result := map[string]string{}
+			for id, version := range sdks {
+				if v, ok := version.(string); ok {
+					result[id] = v
+				}
+			}
+			return result
+		}
+	}
+
+	return nil
+}
+
+// restoreMsbuildSdks downloads the MSBuild project SDK packages into the
+// packages directory when BP_DOTNET_RESTORE_MSBUILD_SDKS is set, so that they
+// are available to builds without access to the package feeds. The packages
+// are downloaded by restoring a generated project that lists each of them as
+// a PackageDownload.
+func restoreMsbuildSdks(sdks map[string]string, dotnetRoot, packagesDir, nugetConfigPath string, dotnetCLI DotnetCLI, logger scribe.Emitter, clock chronos.Clock) error {
+	value, ok := os.LookupEnv(RestoreMsbuildSdks)
+	if !ok || len(sdks) == 0 {
+		return nil
+	}
+
+	restore, err := strconv.ParseBool(value)
+	if err != nil {
+		return fmt.Errorf("invalid value '%s' for %s: must be true or false", value, RestoreMsbuildSdks)
+	}
+
+	if !restore {
+		return nil
+	}
+
+	var ids []string
+	for id := range sdks {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var downloads strings.Builder
+	for _, id := range ids {
+		fmt.Fprintf(&downloads, "    <PackageDownload Include=%q Version=\"[%s]\" />\n", id, sdks[id])
+	}
+
+	projectDir, err := os.MkdirTemp("", "msbuild-sdks")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(projectDir)
+
+	projectPath := filepath.Join(projectDir, "msbuild-sdks.proj")
+	err = os.WriteFile(projectPath, []byte(fmt.Sprintf(`<Project Sdk="Microsoft.NET.Sdk">
+  <PropertyGroup>
+    <TargetFramework>netstandard2.0</TargetFramework>
+  </PropertyGroup>
+  <ItemGroup>
+%s  </ItemGroup>
+</Project>
+`, downloads.String())), 0600)
+	if err != nil {
+		return fmt.Errorf("failed to write msbuild-sdks.proj: %w", err)
+	}
+
+	args := []string{"restore", projectPath, "--packages", packagesDir}
+	if nugetConfigPath != "" {
+		args = append(args, "--configfile", nugetConfigPath)
+	}
+
+	logger.Subprocess("Restoring MSBuild project SDKs %s", strings.Join(ids, ", "))
+	duration, err := clock.Measure(func() error {
+		return dotnetCLI.Execute(dotnetRoot, projectDir, nil, args...)
+	})
+	if err != nil {
+		return err
+	}
+
+	logger.Action("Completed in %s", duration.Round(time.Millisecond))
+	logger.Break()
+
+	return nil
+}
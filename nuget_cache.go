@@ -0,0 +1,107 @@
+package dotnetcoresdk
+
+import (
+	"fmt"
+	"io/fs"
+	"maps"
+	"path/filepath"
+	"slices"
+
+	"github.com/paketo-buildpacks/packit/v2"
+	pfs "github.com/paketo-buildpacks/packit/v2/fs"
+	"github.com/paketo-buildpacks/packit/v2/scribe"
+)
+
+// FindRestoreInputs returns the paths of the files that determine which NuGet
+// packages are restored for the application: packages.lock.json,
+// Directory.Packages.props, global.json, which declares MSBuild project SDKs,
+// dotnet-tools.json, which lists local tools, and project files. Build output
+// directories are skipped.
+func FindRestoreInputs(dir string) ([]string, error) {
+	var inputs []string
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if entry.IsDir() {
+			switch entry.Name() {
+			case "bin", "obj", ".git", "node_modules":
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		name := entry.Name()
+		if name == "packages.lock.json" || name == "Directory.Packages.props" || name == "global.json" || name == "dotnet-tools.json" || slices.Contains([]string{".csproj", ".fsproj", ".vbproj"}, filepath.Ext(name)) {
+			inputs = append(inputs, path)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find NuGet restore inputs: %w", err)
+	}
+
+	return inputs, nil
+}
+
+// contributeNugetCache returns the nuget-cache layer, which holds the NuGet
+// global packages folder across builds. The layer records the checksum of each
+// restore input and is reset when any of them change, so that packages that
+// are no longer referenced do not accumulate. It returns nil when the
+// application has no restore inputs.
+func contributeNugetCache(context packit.BuildContext, logger scribe.Emitter) (*packit.Layer, error) {
+	inputs, err := FindRestoreInputs(context.WorkingDir)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(inputs) == 0 {
+		return nil, nil
+	}
+
+	calculator := pfs.NewChecksumCalculator()
+	inputChecksums := map[string]interface{}{}
+	for _, input := range inputs {
+		checksum, err := calculator.Sum(input)
+		if err != nil {
+			return nil, err
+		}
+
+		relativePath, err := filepath.Rel(context.WorkingDir, input)
+		if err != nil {
+			return nil, err
+		}
+		inputChecksums[filepath.ToSlash(relativePath)] = checksum
+	}
+
+	cacheLayer, err := context.Layers.Get("nuget-cache")
+	if err != nil {
+		return nil, err
+	}
+
+	cachedChecksums, _ := cacheLayer.Metadata["input-checksums"].(map[string]interface{})
+	if maps.Equal(cachedChecksums, inputChecksums) {
+		logger.Process(fmt.Sprintf("Reusing cached layer %s", cacheLayer.Path))
+		logger.Break()
+	} else {
+		logger.Process("Creating NuGet package cache")
+		cacheLayer, err = cacheLayer.Reset()
+		if err != nil {
+			return nil, err
+		}
+		logger.Break()
+	}
+
+	cacheLayer.Metadata = map[string]interface{}{
+		"input-checksums": inputChecksums,
+	}
+
+	// The layer is available during the build so that later buildpacks restore
+	// into it, but it is never exported to the application image
+	cacheLayer.Build, cacheLayer.Cache = true, true
+	cacheLayer.BuildEnv.Override("NUGET_PACKAGES", cacheLayer.Path)
+
+	return &cacheLayer, nil
+}
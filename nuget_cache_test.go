@@ -0,0 +1,55 @@
+package dotnetcoresdk_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	dotnetcoresdk "github.com/paketo-buildpacks/dotnet-core-sdk"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testNugetCache(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		workingDir string
+	)
+
+	it.Before(func() {
+		workingDir = t.TempDir()
+	})
+
+	context("FindRestoreInputs", func() {
+		it.Before(func() {
+			for _, name := range []string{
+				"Directory.Packages.props",
+				"README.md",
+				filepath.Join(".config", "dotnet-tools.json"),
+				filepath.Join("src", "app", "app.csproj"),
+				filepath.Join("src", "app", "packages.lock.json"),
+				filepath.Join("src", "app", "Program.cs"),
+				filepath.Join("src", "lib", "lib.fsproj"),
+				filepath.Join("src", "app", "obj", "project.assets.json"),
+				filepath.Join("src", "app", "bin", "Release", "copy.csproj"),
+			} {
+				Expect(os.MkdirAll(filepath.Dir(filepath.Join(workingDir, name)), os.ModePerm)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(workingDir, name), []byte(""), 0644)).To(Succeed())
+			}
+		})
+
+		it("returns the lock files, central package management files, tool manifests and project files", func() {
+			inputs, err := dotnetcoresdk.FindRestoreInputs(workingDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(inputs).To(Equal([]string{
+				filepath.Join(workingDir, ".config", "dotnet-tools.json"),
+				filepath.Join(workingDir, "Directory.Packages.props"),
+				filepath.Join(workingDir, "src", "app", "app.csproj"),
+				filepath.Join(workingDir, "src", "app", "packages.lock.json"),
+				filepath.Join(workingDir, "src", "lib", "lib.fsproj"),
+			}))
+		})
+	})
+}
@@ -0,0 +1,83 @@
+package dotnetcoresdk
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/paketo-buildpacks/packit/v2"
+	"github.com/paketo-buildpacks/packit/v2/scribe"
+	"github.com/paketo-buildpacks/packit/v2/servicebindings"
+)
+
+// NugetConfigBindingType is the type of the service binding that provides a
+// NuGet.Config, e.g. with private package sources and their credentials.
+const NugetConfigBindingType = "nugetconfig"
+
+// contributeNugetConfig writes the NuGet.Config from the nugetconfig service
+// binding into the user configuration directory of a build-only layer, and
+// points DOTNET_CLI_HOME at it so that NuGet picks the file up. The layer is
+// neither cached nor exported and has no SBOM, so the credentials it may
+// contain do not outlive the build. It returns nil when there is no binding.
+func contributeNugetConfig(context packit.BuildContext, logger scribe.Emitter) (*packit.Layer, string, error) {
+	bindings, err := servicebindings.NewResolver().Resolve(NugetConfigBindingType, "", context.Platform.Path)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to resolve %s service binding: %w", NugetConfigBindingType, err)
+	}
+
+	if len(bindings) == 0 {
+		return nil, "", nil
+	}
+
+	if len(bindings) > 1 {
+		return nil, "", fmt.Errorf("found %d bindings of type '%s' but expected at most 1", len(bindings), NugetConfigBindingType)
+	}
+
+	binding := bindings[0]
+
+	var entry *servicebindings.Entry
+	for key, value := range binding.Entries {
+		if strings.EqualFold(key, "nuget.config") {
+			entry = value
+			break
+		}
+	}
+	if entry == nil {
+		return nil, "", fmt.Errorf("binding '%s' of type '%s' does not contain a nuget.config entry", binding.Name, NugetConfigBindingType)
+	}
+
+	content, err := entry.ReadBytes()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read nuget.config from binding '%s': %w", binding.Name, err)
+	}
+
+	logger.Process("Configuring NuGet from binding '%s'", binding.Name)
+
+	configLayer, err := context.Layers.Get("nuget-config")
+	if err != nil {
+		return nil, "", err
+	}
+
+	configLayer, err = configLayer.Reset()
+	if err != nil {
+		return nil, "", err
+	}
+
+	configPath := filepath.Join(configLayer.Path, ".nuget", "NuGet", "NuGet.Config")
+	err = os.MkdirAll(filepath.Dir(configPath), os.ModePerm)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create NuGet configuration directory: %w", err)
+	}
+
+	err = os.WriteFile(configPath, content, 0600)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to write NuGet.Config: %w", err)
+	}
+
+	configLayer.Build = true
+	configLayer.BuildEnv.Override("DOTNET_CLI_HOME", configLayer.Path)
+	logger.EnvironmentVariables(configLayer)
+
+	return &configLayer, configPath, nil
+}
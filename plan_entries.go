@@ -0,0 +1,53 @@
+package dotnetcoresdk
+
+import (
+	"reflect"
+	"regexp"
+
+	"github.com/paketo-buildpacks/packit/v2"
+)
+
+// PriorityRank returns the index in Priorities matched by the given
+// version-source, using the same matching rules as draft.Planner. Lower ranks
+// have higher priority. Unmatched sources rank after every priority.
+func PriorityRank(versionSource string) int {
+	for index, match := range Priorities {
+		if r, ok := match.(*regexp.Regexp); ok {
+			if r.MatchString(versionSource) {
+				return index
+			}
+		} else if reflect.DeepEqual(match, versionSource) {
+			return index
+		}
+	}
+
+	return len(Priorities)
+}
+
+// SelectSdkEntries returns the plan entries that should each be installed as
+// an SDK. When the highest priority entry has a version-source, every entry
+// sharing its priority is selected, which allows several SDKs to be installed
+// side by side (e.g. a list-valued BP_DOTNET_SDK_VERSION or several project
+// files). Otherwise only the highest priority entry is selected.
+func SelectSdkEntries(planEntry packit.BuildpackPlanEntry, entries []packit.BuildpackPlanEntry) []packit.BuildpackPlanEntry {
+	versionSource, _ := planEntry.Metadata["version-source"].(string)
+	if versionSource == "" {
+		return []packit.BuildpackPlanEntry{planEntry}
+	}
+
+	rank := PriorityRank(versionSource)
+
+	var selected []packit.BuildpackPlanEntry
+	for _, entry := range entries {
+		entrySource, _ := entry.Metadata["version-source"].(string)
+		if entrySource != "" && PriorityRank(entrySource) == rank {
+			selected = append(selected, entry)
+		}
+	}
+
+	if len(selected) == 0 {
+		return []packit.BuildpackPlanEntry{planEntry}
+	}
+
+	return selected
+}
@@ -0,0 +1,35 @@
+package dotnetcoresdk
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ProjectDir returns the directory that drives SDK selection. This is the
+// BP_DOTNET_PROJECT_PATH subdirectory of the working directory when set,
+// otherwise the working directory itself.
+func ProjectDir(workingDir string) (string, error) {
+	projectPath, ok := os.LookupEnv(ProjectPath)
+	if !ok || projectPath == "" {
+		return workingDir, nil
+	}
+
+	projectDir := filepath.Join(workingDir, projectPath)
+	relativePath, err := filepath.Rel(workingDir, projectDir)
+	if err != nil || relativePath == ".." || strings.HasPrefix(relativePath, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid value '%s' for %s: must be a directory inside the application", projectPath, ProjectPath)
+	}
+
+	info, err := os.Stat(projectDir)
+	if err != nil {
+		return "", fmt.Errorf("invalid value '%s' for %s: %w", projectPath, ProjectPath, err)
+	}
+
+	if !info.IsDir() {
+		return "", fmt.Errorf("invalid value '%s' for %s: must be a directory inside the application", projectPath, ProjectPath)
+	}
+
+	return projectDir, nil
+}
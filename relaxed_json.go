@@ -0,0 +1,120 @@
+package dotnetcoresdk
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// JSONSyntaxError is returned for malformed JSON, with the line and column
+// (both starting at 1) where the problem was found.
+type JSONSyntaxError struct {
+	Line   int
+	Column int
+	Err    error
+}
+
+func (e JSONSyntaxError) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Err)
+}
+
+func (e JSONSyntaxError) Unwrap() error {
+	return e.Err
+}
+
+// unmarshalRelaxedJSON decodes JSON that may contain // and /* */ comments
+// and trailing commas, the grammar the .NET host accepts for global.json.
+func unmarshalRelaxedJSON(content []byte, v interface{}) error {
+	normalized, err := stripRelaxedJSON(content)
+	if err != nil {
+		return err
+	}
+
+	err = json.Unmarshal(normalized, v)
+	if err != nil {
+		var syntaxError *json.SyntaxError
+		if errors.As(err, &syntaxError) {
+			return newJSONSyntaxError(content, syntaxError.Offset-1, err)
+		}
+
+		var typeError *json.UnmarshalTypeError
+		if errors.As(err, &typeError) {
+			return newJSONSyntaxError(content, typeError.Offset-1, err)
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// stripRelaxedJSON replaces comments and trailing commas with whitespace.
+// Newlines are kept, so offsets into the result are offsets into content.
+func stripRelaxedJSON(content []byte) ([]byte, error) {
+	result := bytes.Clone(content)
+
+	inString := false
+	for i := 0; i < len(result); i++ {
+		c := result[i]
+
+		if inString {
+			switch c {
+			case '\\':
+				i++
+			case '"':
+				inString = false
+			}
+			continue
+		}
+
+		switch {
+		case c == '"':
+			inString = true
+
+		case c == '/' && i+1 < len(result) && result[i+1] == '/':
+			for ; i < len(result) && result[i] != '\n'; i++ {
+				result[i] = ' '
+			}
+
+		case c == '/' && i+1 < len(result) && result[i+1] == '*':
+			start := i
+			end := bytes.Index(result[i+2:], []byte("*/"))
+			if end == -1 {
+				return nil, newJSONSyntaxError(content, int64(start), errors.New("unterminated comment"))
+			}
+
+			for end = i + 2 + end + 2; i < end; i++ {
+				if result[i] != '\n' {
+					result[i] = ' '
+				}
+			}
+			i--
+
+		case c == '}' || c == ']':
+			j := i - 1
+			for j >= 0 && isJSONWhitespace(result[j]) {
+				j--
+			}
+			if j >= 0 && result[j] == ',' {
+				result[j] = ' '
+			}
+		}
+	}
+
+	return result, nil
+}
+
+func isJSONWhitespace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+func newJSONSyntaxError(content []byte, offset int64, err error) JSONSyntaxError {
+	offset = max(0, min(offset, int64(len(content))))
+
+	before := content[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	column := int(offset) - bytes.LastIndexByte(before, '\n')
+
+	return JSONSyntaxError{Line: line, Column: column, Err: err}
+}
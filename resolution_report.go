@@ -0,0 +1,108 @@
+package dotnetcoresdk
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/paketo-buildpacks/packit/v2"
+	"github.com/paketo-buildpacks/packit/v2/scribe"
+)
+
+// ResolutionReport explains how the SDK version was chosen: every candidate
+// version source with its priority, and for each installed SDK the
+// constraints that were tried and the version they resolved to.
+type ResolutionReport struct {
+	Candidates  []ResolutionCandidate `json:"candidates"`
+	Resolutions []Resolution          `json:"resolutions"`
+}
+
+type ResolutionCandidate struct {
+	VersionSource string `json:"version-source"`
+	Constraint    string `json:"constraint"`
+
+	// PriorityRank is the index of the matching entry in Priorities, where 0
+	// is the highest priority
+	PriorityRank int  `json:"priority-rank"`
+	Selected     bool `json:"selected"`
+}
+
+type Resolution struct {
+	VersionSource    string   `json:"version-source"`
+	Constraint       string   `json:"constraint"`
+	RollForward      string   `json:"roll-forward,omitempty"`
+	ConstraintsTried []string `json:"constraints-tried"`
+	Version          string   `json:"version"`
+}
+
+// NewResolutionReport lists the given plan entries as candidates, marking the
+// selected ones.
+func NewResolutionReport(entries, selected []packit.BuildpackPlanEntry) ResolutionReport {
+	report := ResolutionReport{
+		Candidates:  []ResolutionCandidate{},
+		Resolutions: []Resolution{},
+	}
+	for _, entry := range entries {
+		versionSource, _ := entry.Metadata["version-source"].(string)
+		constraint, _ := entry.Metadata["version"].(string)
+
+		isSelected := false
+		for _, s := range selected {
+			sSource, _ := s.Metadata["version-source"].(string)
+			sConstraint, _ := s.Metadata["version"].(string)
+			if sSource == versionSource && sConstraint == constraint {
+				isSelected = true
+				break
+			}
+		}
+
+		report.Candidates = append(report.Candidates, ResolutionCandidate{
+			VersionSource: versionSource,
+			Constraint:    constraint,
+			PriorityRank:  PriorityRank(versionSource),
+			Selected:      isSelected,
+		})
+	}
+
+	return report
+}
+
+// Log prints the report at the DEBUG level.
+func (r ResolutionReport) Log(logger scribe.Emitter) {
+	logger.Debug.Subprocess("SDK resolution report:")
+	for _, candidate := range r.Candidates {
+		selected := ""
+		if candidate.Selected {
+			selected = " (selected)"
+		}
+		logger.Debug.Action("Candidate %q from %q at priority rank %d%s", candidate.Constraint, sourceName(candidate.VersionSource), candidate.PriorityRank, selected)
+	}
+
+	for _, resolution := range r.Resolutions {
+		logger.Debug.Action("Resolved %q from %q to %s", resolution.Constraint, sourceName(resolution.VersionSource), resolution.Version)
+		if resolution.RollForward != "" {
+			logger.Debug.Detail("Roll-forward policy: %s", resolution.RollForward)
+		}
+		for i, constraint := range resolution.ConstraintsTried {
+			logger.Debug.Detail("Constraint %d: %s", i+1, constraint)
+		}
+	}
+	logger.Debug.Break()
+}
+
+// JSON returns the report serialized as JSON for the layer metadata.
+func (r ResolutionReport) JSON() (string, error) {
+	content, err := json.Marshal(r)
+	if err != nil {
+		return "", fmt.Errorf("failed to serialize resolution report: %w", err)
+	}
+
+	return string(content), nil
+}
+
+func sourceName(versionSource string) string {
+	if versionSource == "" {
+		return "<unknown>"
+	}
+
+	return versionSource
+}
@@ -15,6 +15,10 @@ import (
 
 type Generator struct{}
 
+func (f Generator) Generate(path string) (sbom.SBOM, error) {
+	return sbom.Generate(path)
+}
+
 func (f Generator) GenerateFromDependency(dependency postal.Dependency, path string) (sbom.SBOM, error) {
 	return sbom.GenerateFromDependency(dependency, path)
 }
@@ -32,6 +36,7 @@ func main() {
 			entryResolver,
 			dependencyManager,
 			Generator{},
+			dotnetcoresdk.NewDotnetExecutable(logEmitter),
 			logEmitter,
 			chronos.DefaultClock,
 		),
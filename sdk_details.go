@@ -0,0 +1,121 @@
+package dotnetcoresdk
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/BurntSushi/toml"
+	"github.com/paketo-buildpacks/packit/v2"
+	"github.com/paketo-buildpacks/packit/v2/paketosbom"
+	"github.com/paketo-buildpacks/packit/v2/postal"
+	"github.com/paketo-buildpacks/packit/v2/scribe"
+)
+
+// SdkDetails are the versions of the Microsoft.NETCore.App and
+// Microsoft.AspNetCore.App runtimes bundled with an SDK, the Visual Studio
+// version it ships with and the C# and F# language versions it supports. Each
+// is empty when buildpack.toml does not record it.
+type SdkDetails struct {
+	RuntimeVersion    string `toml:"runtime-version"`
+	AspNetCoreVersion string `toml:"aspnetcore-version"`
+	VSVersion         string `toml:"vs-version"`
+	CSharpVersion     string `toml:"csharp-version"`
+	FSharpVersion     string `toml:"fsharp-version"`
+}
+
+// LoadSdkDetails reads the details of the given dependency from the
+// sdk-details table of the buildpack.toml at path, which the dependency
+// update workflow fills from the release metadata. The details are recorded
+// per SDK version rather than on the dependencies, whose extra fields the
+// workflow does not keep. No details are returned when there is no
+// buildpack.toml or no entry for the version.
+func LoadSdkDetails(path string, dependency postal.Dependency) (SdkDetails, error) {
+	var buildpackTOML struct {
+		Metadata struct {
+			SdkDetails []struct {
+				Version string `toml:"version"`
+				SdkDetails
+			} `toml:"sdk-details"`
+		} `toml:"metadata"`
+	}
+
+	_, err := toml.DecodeFile(path, &buildpackTOML)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return SdkDetails{}, nil
+		}
+		return SdkDetails{}, err
+	}
+
+	for _, d := range buildpackTOML.Metadata.SdkDetails {
+		if d.Version == dependency.Version {
+			return d.SdkDetails, nil
+		}
+	}
+
+	return SdkDetails{}, nil
+}
+
+// BundledRuntimes returns the bundled runtime versions keyed by the names of
+// the dependencies that provide them, or nil when none are known.
+func (d SdkDetails) BundledRuntimes() map[string]interface{} {
+	if d.RuntimeVersion == "" && d.AspNetCoreVersion == "" {
+		return nil
+	}
+
+	runtimes := map[string]interface{}{}
+	if d.RuntimeVersion != "" {
+		runtimes["dotnet-runtime"] = d.RuntimeVersion
+	}
+	if d.AspNetCoreVersion != "" {
+		runtimes["dotnet-aspnetcore"] = d.AspNetCoreVersion
+	}
+
+	return runtimes
+}
+
+// BOMEntries returns a BOM entry for each known bundled runtime.
+func (d SdkDetails) BOMEntries() []packit.BOMEntry {
+	var entries []packit.BOMEntry
+	if d.RuntimeVersion != "" {
+		entries = append(entries, packit.BOMEntry{
+			Name:     "dotnet-runtime",
+			Metadata: paketosbom.BOMMetadata{Version: d.RuntimeVersion},
+		})
+	}
+	if d.AspNetCoreVersion != "" {
+		entries = append(entries, packit.BOMEntry{
+			Name:     "dotnet-aspnetcore",
+			Metadata: paketosbom.BOMMetadata{Version: d.AspNetCoreVersion},
+		})
+	}
+
+	return entries
+}
+
+// Log lists the known details of the SDK with the given version.
+func (d SdkDetails) Log(version string, logger scribe.Emitter) {
+	var details []string
+	for _, detail := range []struct{ name, version string }{
+		{".NET Runtime", d.RuntimeVersion},
+		{"ASP.NET Core Runtime", d.AspNetCoreVersion},
+		{"C#", d.CSharpVersion},
+		{"F#", d.FSharpVersion},
+		{"Visual Studio", d.VSVersion},
+	} {
+		if detail.version != "" {
+			details = append(details, fmt.Sprintf("%s %s", detail.name, detail.version))
+		}
+	}
+
+	if len(details) == 0 {
+		return
+	}
+
+	logger.Subprocess(".NET Core SDK %s includes:", version)
+	for _, detail := range details {
+		logger.Action("%s", detail)
+	}
+	logger.Break()
+}
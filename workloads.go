@@ -0,0 +1,105 @@
+package dotnetcoresdk
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/paketo-buildpacks/packit/v2/scribe"
+)
+
+var platformWorkloadPattern = regexp.MustCompile(`^net\d+\.\d+-(android|ios|maccatalyst|macos|tvos)(?:\d+(?:\.\d+)*)?$`)
+
+// linuxWorkloads are the inferred workloads that can be installed on the
+// Linux build image. The Apple platform workloads, and the MAUI workloads
+// for them, are only available on macOS and Windows.
+var linuxWorkloads = []string{"android", "maui-android", "wasm-tools"}
+
+// Workloads returns the .NET workloads needed to build the project, inferred
+// from its MSBuild properties and platform specific target frameworks. A MAUI
+// project needs the MAUI workload of each of its platforms.
+func (p ProjectFile) Workloads() []string {
+	properties := p.Properties()
+	useMaui := isTrue(properties.Get("UseMaui"))
+
+	var workloads []string
+	for _, framework := range p.TargetFrameworks() {
+		matches := platformWorkloadPattern.FindStringSubmatch(strings.ToLower(framework))
+		if matches == nil {
+			continue
+		}
+
+		if useMaui {
+			workloads = append(workloads, fmt.Sprintf("maui-%s", matches[1]))
+		} else {
+			workloads = append(workloads, matches[1])
+		}
+	}
+
+	if useMaui && len(workloads) == 0 {
+		workloads = append(workloads, "maui")
+	}
+
+	if isTrue(properties.Get("RunAOTCompilation")) || isTrue(properties.Get("WasmBuildNative")) {
+		workloads = append(workloads, "wasm-tools")
+	}
+
+	return workloads
+}
+
+// ResolveWorkloads returns the sorted list of workloads to install into the
+// SDK layer. The BP_DOTNET_WORKLOADS setting takes precedence; otherwise the
+// workloads are inferred from the project files in projectDir, which inherit
+// Directory.Build.props files no higher than the root directory. Inferred
+// workloads that are not available on Linux are logged and left out.
+func ResolveWorkloads(projectDir, root string, logger scribe.Emitter) ([]string, error) {
+	if value, ok := os.LookupEnv(Workloads); ok {
+		workloads := strings.FieldsFunc(value, func(r rune) bool {
+			return r == ',' || r == ' '
+		})
+		slices.Sort(workloads)
+
+		return slices.Compact(workloads), nil
+	}
+
+	projectFiles, err := FindProjectFiles(projectDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var inferred []string
+	for _, path := range projectFiles {
+		projectFile, err := ParseProjectFile(path, root)
+		if err != nil {
+			return nil, err
+		}
+
+		inferred = append(inferred, projectFile.Workloads()...)
+	}
+
+	slices.Sort(inferred)
+	inferred = slices.Compact(inferred)
+
+	var workloads, skipped []string
+	for _, workload := range inferred {
+		if slices.Contains(linuxWorkloads, workload) {
+			workloads = append(workloads, workload)
+		} else {
+			skipped = append(skipped, workload)
+		}
+	}
+
+	if len(skipped) > 0 {
+		logger.Subprocess("Skipping workloads %s, which are not available on Linux", strings.Join(skipped, ", "))
+	}
+
+	return workloads, nil
+}
+
+func isTrue(value string) bool {
+	b, _ := strconv.ParseBool(strings.TrimSpace(value))
+	return b
+}
@@ -0,0 +1,136 @@
+package dotnetcoresdk_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	dotnetcoresdk "github.com/paketo-buildpacks/dotnet-core-sdk"
+	"github.com/paketo-buildpacks/packit/v2/scribe"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testWorkloads(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		workingDir string
+		buffer     *bytes.Buffer
+		logger     scribe.Emitter
+	)
+
+	it.Before(func() {
+		workingDir = t.TempDir()
+		buffer = bytes.NewBuffer(nil)
+		logger = scribe.NewEmitter(buffer)
+	})
+
+	context("ResolveWorkloads", func() {
+		context("when BP_DOTNET_WORKLOADS is set", func() {
+			it.Before(func() {
+				Expect(os.Setenv("BP_DOTNET_WORKLOADS", "wasm-tools, maui aspire,maui")).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(workingDir, "app.csproj"), []byte(`<Project>
+					<PropertyGroup>
+						<TargetFramework>net8.0-android</TargetFramework>
+					</PropertyGroup>
+				</Project>`), 0644)).To(Succeed())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_DOTNET_WORKLOADS")).To(Succeed())
+			})
+
+			it("returns the configured workloads instead of the detected ones", func() {
+				workloads, err := dotnetcoresdk.ResolveWorkloads(workingDir, workingDir, logger)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(workloads).To(Equal([]string{"aspire", "maui", "wasm-tools"}))
+			})
+		})
+
+		context("when the project files require workloads", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "app.csproj"), []byte(`<Project Sdk="Microsoft.NET.Sdk">
+					<PropertyGroup>
+						<TargetFrameworks>net8.0-android;net8.0-ios;net8.0-maccatalyst</TargetFrameworks>
+						<UseMaui>true</UseMaui>
+					</PropertyGroup>
+				</Project>`), 0644)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(workingDir, "binding.csproj"), []byte(`<Project Sdk="Microsoft.NET.Sdk">
+					<PropertyGroup>
+						<TargetFrameworks>net9.0-android;net9.0-ios18.0</TargetFrameworks>
+					</PropertyGroup>
+				</Project>`), 0644)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(workingDir, "client.csproj"), []byte(`<Project Sdk="Microsoft.NET.Sdk.BlazorWebAssembly">
+					<PropertyGroup>
+						<TargetFramework>net9.0</TargetFramework>
+						<RunAOTCompilation>True</RunAOTCompilation>
+					</PropertyGroup>
+				</Project>`), 0644)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(workingDir, "host.csproj"), []byte(`<Project Sdk="Microsoft.NET.Sdk">
+					<PropertyGroup>
+						<TargetFramework>net9.0</TargetFramework>
+						<IsAspireHost>true</IsAspireHost>
+						<UseMaui>false</UseMaui>
+					</PropertyGroup>
+				</Project>`), 0644)).To(Succeed())
+			})
+
+			it("returns the detected workloads that are available on Linux", func() {
+				workloads, err := dotnetcoresdk.ResolveWorkloads(workingDir, workingDir, logger)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(workloads).To(Equal([]string{"android", "maui-android", "wasm-tools"}))
+				Expect(buffer.String()).To(ContainSubstring("Skipping workloads ios, maui-ios, maui-maccatalyst, which are not available on Linux"))
+			})
+		})
+
+		context("when a MAUI project has no platform target frameworks", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "app.csproj"), []byte(`<Project Sdk="Microsoft.NET.Sdk">
+					<PropertyGroup>
+						<TargetFramework>net8.0</TargetFramework>
+						<UseMaui>true</UseMaui>
+					</PropertyGroup>
+				</Project>`), 0644)).To(Succeed())
+			})
+
+			it("skips the maui workload", func() {
+				workloads, err := dotnetcoresdk.ResolveWorkloads(workingDir, workingDir, logger)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(workloads).To(BeEmpty())
+				Expect(buffer.String()).To(ContainSubstring("Skipping workloads maui, which are not available on Linux"))
+			})
+		})
+
+		context("when no workloads are required", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "app.csproj"), []byte(`<Project Sdk="Microsoft.NET.Sdk">
+					<PropertyGroup>
+						<TargetFramework>net8.0-windows</TargetFramework>
+					</PropertyGroup>
+				</Project>`), 0644)).To(Succeed())
+			})
+
+			it("returns nothing", func() {
+				workloads, err := dotnetcoresdk.ResolveWorkloads(workingDir, workingDir, logger)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(workloads).To(BeEmpty())
+			})
+		})
+
+		context("failure cases", func() {
+			context("when a project file cannot be parsed", func() {
+				it.Before(func() {
+					Expect(os.WriteFile(filepath.Join(workingDir, "app.csproj"), []byte(`<Project`), 0644)).To(Succeed())
+				})
+
+				it("returns an error", func() {
+					_, err := dotnetcoresdk.ResolveWorkloads(workingDir, workingDir, logger)
+					Expect(err).To(MatchError(ContainSubstring("failed to parse app.csproj")))
+				})
+			})
+		})
+	})
+}